@@ -0,0 +1,161 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stbraun/shrinkr/util"
+	"golang.org/x/net/html"
+)
+
+var (
+	analyzeAsJSON   bool
+	candidatesLimit int
+)
+
+// Result of analyzing a single document.
+type analysis struct {
+	File       string             `json:"file"`
+	Metadata   util.Metadata      `json:"metadata"`
+	Sizes      util.SizeBreakdown `json:"sizes"`
+	Candidates []util.Candidate   `json:"candidates"`
+	Passes     []passSaving       `json:"passes"`
+}
+
+// analyzeCmd represents the analyze command
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze <filename>",
+	Short: "Explains the structure of a clipped document.",
+	Long: `The command prints a breakdown of the document by size, the candidate elements
+which may hold the main content and the saving each cleanup pass would achieve.
+It does not modify the document.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+		file := util.OpenFile(filename)
+		defer func() { _ = file.Close() }()
+
+		doc, err := html.Parse(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		info, err := newDocument(doc, filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		result := analysis{
+			File:       filename,
+			Metadata:   info.metadata,
+			Sizes:      util.AnalyzeSizes(doc, info.strategies),
+			Candidates: util.ContentCandidates(doc, candidatesLimit),
+			Passes:     estimatePassSavings(doc, info),
+		}
+		if analyzeAsJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(result); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+		reportAnalysis(result)
+	},
+}
+
+func reportAnalysis(a analysis) {
+	fmt.Printf("\n--------\nAnalysis of %s\n--------\n", a.File)
+	reportMetadata(a.Metadata)
+	share := func(size int64) float64 {
+		if a.Sizes.Total == 0 {
+			return 0
+		}
+		return float64(size) * 100 / float64(a.Sizes.Total)
+	}
+	rows := []struct {
+		label string
+		size  int64
+	}{
+		{"total", a.Sizes.Total},
+		{"head", a.Sizes.Head},
+		{"scripts", a.Sizes.Scripts},
+		{"styles", a.Sizes.Styles},
+		{"images", a.Sizes.Images},
+		{"article", a.Sizes.Article},
+		{"siblings to be removed", a.Sizes.Removable},
+	}
+	for _, r := range rows {
+		fmt.Printf("%-24s %12s %6.1f%%\n", r.label, formatSize(r.size), share(r.size))
+	}
+	fmt.Printf("\nCandidate content roots\n")
+	for i, c := range a.Candidates {
+		fmt.Printf("%2d. score %7.1f  text %6d  paragraphs %3d  links %3.0f%%  %s\n",
+			i+1, c.Score, c.TextLength, c.Paragraphs, c.LinkDensity*100, c.Path)
+	}
+	fmt.Printf("\nCleanup passes\n")
+	for _, p := range a.Passes {
+		fmt.Printf("%-24s saves %12s  %s\n", p.Name, formatSize(p.Saved), p.Description)
+	}
+	fmt.Println("--------")
+}
+
+func reportMetadata(md util.Metadata) {
+	published := ""
+	if md.Published != nil {
+		published = md.Published.Format(time.RFC3339)
+	}
+	fields := []struct {
+		label string
+		value string
+	}{
+		{"title", md.Title},
+		{"authors", strings.Join(md.Authors, ", ")},
+		{"site", md.SiteName},
+		{"canonical URL", md.CanonicalURL},
+		{"language", md.Language},
+		{"tags", strings.Join(md.Tags, ", ")},
+		{"published", published},
+		{"lead image", md.LeadImage},
+	}
+	for _, f := range fields {
+		if f.value != "" {
+			fmt.Printf("%-24s %s\n", f.label, f.value)
+		}
+	}
+	fmt.Println()
+}
+
+func init() {
+	rootCmd.AddCommand(analyzeCmd)
+
+	analyzeCmd.PersistentFlags().BoolVar(&analyzeAsJSON, "json", false, "Print the analysis as JSON.")
+	analyzeCmd.PersistentFlags().IntVar(&candidatesLimit, "candidates", 5, "Maximum number of candidate content roots to list.")
+}
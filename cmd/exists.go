@@ -22,8 +22,12 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -31,37 +35,174 @@ import (
 	"golang.org/x/net/html"
 )
 
+// Exit codes of the exists command. With several files the most severe one is returned.
+const (
+	exitFound      = 0
+	exitNotFound   = 1
+	exitParseError = 2
+	exitIOError    = 3
+	exitUsageError = 4
+)
+
+var (
+	existsAsJSON bool
+	existsQuiet  bool
+)
+
+// Result of checking a single document.
+type existsResult struct {
+	File     string `json:"file"`
+	Found    bool   `json:"found"`
+	Strategy string `json:"strategy,omitempty"`
+	Path     string `json:"path,omitempty"`
+	// Version of shrinkr which produced the document, empty for original clippings.
+	ShrunkBy string `json:"shrunkBy,omitempty"`
+	Error    string `json:"error,omitempty"`
+	ExitCode int    `json:"exitCode"`
+}
+
 // existsCmd represents the exists command
 var existsCmd = &cobra.Command{
-	Use:   "exists <filename>",
-	Short: "Looks for an element of type article in a given document.",
-	Long: `The command checks for the existence of an element of type article in a given HTML document.
-It can be run on documents to decide whether shrinking them may work.`,
-	Args: cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		filename := args[0]
-		if viper.GetBool("verbose") {
-			fmt.Println("exists called for " + filename)
-		}
-		file := util.OpenFile(filename)
-		defer func() { _ = file.Close() }()
+	Use:   "exists <filename or glob pattern>...",
+	Short: "Looks for the main content in the given documents.",
+	Long: `The command checks whether one of the configured extraction strategies or those of the
+site profile finds the main content in the given HTML documents, by default an element
+of type article.
+It can be run on documents to decide whether shrinking them may work.
 
-		doc, err := html.Parse(file)
-		if err != nil {
+Exit codes: 0 content found in all documents, 1 content not found,
+2 a document could not be parsed, 3 a document could not be read,
+4 an unknown strategy or profile was configured.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := contentStrategies(nil); err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			os.Exit(exitUsageError)
 		}
-		result := util.HasArticleElement(doc)
-		if result {
-			fmt.Println("Document contains an <article> element.")
-			os.Exit(0)
-		} else {
-			fmt.Println("Document does not contain an <article> element.")
-			os.Exit(1)
+		var results []existsResult
+		exitCode := exitFound
+		for _, filename := range expandPatterns(args) {
+			if viper.GetBool("verbose") {
+				fmt.Fprintln(os.Stderr, "exists called for "+filename)
+			}
+			r := checkContentExists(filename)
+			results = append(results, r)
+			exitCode = max(exitCode, r.ExitCode)
 		}
+		switch {
+		case existsQuiet:
+		case existsAsJSON:
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.SetEscapeHTML(false)
+			_ = enc.Encode(results)
+		case len(results) == 1:
+			reportExists(results[0])
+		default:
+			reportExistsSummary(results)
+		}
+		os.Exit(exitCode)
 	},
 }
 
+// Expand the given glob patterns. Arguments not matching any file are kept as they are
+// so that they are reported as missing.
+func expandPatterns(patterns []string) []string {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil || len(matches) == 0 {
+			files = append(files, pattern)
+			continue
+		}
+		files = append(files, matches...)
+	}
+	return files
+}
+
+// Check a single document with the strategies of its profile and the configured ones.
+func checkContentExists(filename string) existsResult {
+	r := existsResult{File: filename}
+	file, err := os.Open(filename)
+	if err != nil {
+		r.Error = err.Error()
+		r.ExitCode = exitIOError
+		if errors.Is(err, fs.ErrNotExist) {
+			r.Error = "file does not exist"
+		}
+		return r
+	}
+	defer func() { _ = file.Close() }()
+
+	doc, err := html.Parse(file)
+	if err != nil {
+		r.Error = err.Error()
+		r.ExitCode = exitParseError
+		return r
+	}
+	if p, ok := util.ReadProvenance(doc); ok {
+		r.ShrunkBy = p.Version
+	}
+	profile, err := selectProfile(doc)
+	if err != nil {
+		r.Error = err.Error()
+		r.ExitCode = exitUsageError
+		return r
+	}
+	strategies, err := contentStrategies(profile)
+	if err != nil {
+		r.Error = err.Error()
+		r.ExitCode = exitUsageError
+		return r
+	}
+	n, s, found := util.LocateContent(doc, strategies)
+	if !found {
+		r.ExitCode = exitNotFound
+		return r
+	}
+	r.Found = true
+	r.Strategy = s.Name
+	r.Path = util.NodePath(n)
+	return r
+}
+
+func reportExists(r existsResult) {
+	switch {
+	case r.Error != "":
+		fmt.Printf("%s: %s\n", r.File, r.Error)
+	case r.Found:
+		fmt.Printf("Document contains main content found by strategy %s: %s\n", r.Strategy, r.Path)
+	default:
+		fmt.Println("Document does not contain main content.")
+	}
+	if r.ShrunkBy != "" {
+		fmt.Printf("Document was already shrunk by shrinkr %s.\n", r.ShrunkBy)
+	}
+}
+
+func reportExistsSummary(results []existsResult) {
+	found, shrunk := 0, 0
+	fmt.Printf("\n%-10s %-10s %-8s %s\n", "RESULT", "STRATEGY", "SHRUNK", "FILE")
+	for _, r := range results {
+		result := "missing"
+		switch {
+		case r.Error != "":
+			result = "error"
+		case r.Found:
+			result = "found"
+			found++
+		}
+		if r.ShrunkBy != "" {
+			shrunk++
+		}
+		fmt.Printf("%-10s %-10s %-8s %s\n", result, r.Strategy, r.ShrunkBy, r.File)
+	}
+	fmt.Printf("----------\n%d of %d documents contain main content, %d already shrunk.\n", found, len(results), shrunk)
+}
+
 func init() {
 	rootCmd.AddCommand(existsCmd)
+
+	existsCmd.PersistentFlags().BoolVar(&existsAsJSON, "json", false, "Print the results as JSON.")
+	existsCmd.PersistentFlags().BoolVarP(&existsQuiet, "quiet", "q", false, "Print nothing, only set the exit code.")
 }
@@ -0,0 +1,125 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/stbraun/shrinkr/util"
+	"golang.org/x/net/html"
+)
+
+// An output file waiting for its keywords, which are known only once the whole batch
+// has been processed.
+type keywordJob struct {
+	// Name of the input in the statistics.
+	name   string
+	output string
+	doc    int
+}
+
+var (
+	keywordLimit  int
+	stopwordFiles []string
+	keywords      *util.KeywordExtractor
+	keywordJobs   []keywordJob
+)
+
+// Create the keyword extractor and load the configured stopword lists.
+func setupKeywords() error {
+	keywords = util.NewKeywordExtractor()
+	keywordJobs = nil
+	for _, spec := range stopwordFiles {
+		lang, path, ok := strings.Cut(spec, "=")
+		if !ok {
+			return fmt.Errorf("invalid stopword setting %q, expected language=file", spec)
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		words, err := util.ReadStopwords(file)
+		_ = file.Close()
+		if err != nil {
+			return fmt.Errorf("reading stopwords from %s failed: %w", path, err)
+		}
+		keywords.AddStopwords(strings.ToLower(lang), words)
+	}
+	return nil
+}
+
+// Add the text kept in the document to the batch, to be tagged by tagOutputs.
+func collectKeywords(doc *html.Node, info *document, name, output string) {
+	text := util.TextContent(keptRoot(doc, info))
+	i := keywords.Add(text, info.reading.Language, info.metadata.Tags)
+	keywordJobs = append(keywordJobs, keywordJob{name: name, output: output, doc: i})
+}
+
+// Write the keywords of each output of the batch into the output and its sidecar
+// and record the final size of the output.
+func tagOutputs() {
+	for _, job := range keywordJobs {
+		tags := keywords.Keywords(job.doc, keywordLimit)
+		if len(tags) == 0 {
+			continue
+		}
+		size, err := addKeywords(job.output, tags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Tagging %s failed with %s.\n", job.output, err)
+			continue
+		}
+		stats.UpdateOutputSize(job.name, size)
+		if writeSidecar {
+			sidecar, err := util.ReadSidecar(job.output)
+			if err == nil {
+				sidecar.Keywords, sidecar.OutputSize = tags, size
+				err = util.WriteSidecar(sidecar)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Tagging the sidecar of %s failed with %s.\n", job.output, err)
+			}
+		}
+		stats.AddCounter("articles tagged", 1)
+	}
+}
+
+// Record the keywords in the <head> of the output file.
+// Returns the new size of the output file.
+func addKeywords(output string, tags []string) (int64, error) {
+	content, err := os.ReadFile(output)
+	if err != nil {
+		return 0, err
+	}
+	doc, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return 0, err
+	}
+	util.SetKeywords(doc, tags)
+	var buf bytes.Buffer
+	if err = html.Render(&buf, doc); err != nil {
+		return 0, err
+	}
+	return int64(buf.Len()), os.WriteFile(output, buf.Bytes(), 0o644)
+}
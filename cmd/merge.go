@@ -0,0 +1,164 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stbraun/shrinkr/util"
+	"golang.org/x/net/html"
+)
+
+// Orders of the parts of a merged document.
+const (
+	orderByDate  = "date"
+	orderAsGiven = "args"
+)
+
+var (
+	mergeOrder string
+	mergeTitle string
+)
+
+// mergeCmd represents the merge command
+var mergeCmd = &cobra.Command{
+	Use:   "merge <filename or glob pattern>...",
+	Short: "Merges the parts of a series into a single document.",
+	Long: `The command shrinks each of the given clippings and combines them into one document
+with a table of contents and a header for each part. The parts are ordered by their
+publish date or, with --order args, as given on the command line.
+The title of the series is derived from the titles of the parts unless given by --title.`,
+	Args: cobra.MinimumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if mergeOrder != orderByDate && mergeOrder != orderAsGiven {
+			return fmt.Errorf("unknown order %q, expected %s or %s", mergeOrder, orderByDate, orderAsGiven)
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var parts []util.SeriesPart
+		var titles, sources []string
+		var isize int64
+		for _, filename := range expandPatterns(args) {
+			part, size, err := shrinkPart(filename)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Processing %s failed with %s.\n", filename, err)
+				os.Exit(1)
+			}
+			parts = append(parts, part)
+			isize += size
+		}
+		if mergeOrder == orderByDate {
+			util.SortPartsByDate(parts)
+		}
+		for _, p := range parts {
+			titles = append(titles, p.Title)
+			sources = append(sources, p.Source)
+		}
+		title := mergeTitle
+		if title == "" {
+			title = util.SeriesTitle(titles)
+		}
+		doc := util.MergeParts(title, parts)
+		util.AddProvenance(doc, util.Provenance{
+			Version:      rootCmd.Version,
+			Source:       strings.Join(sources, " "),
+			Processed:    time.Now(),
+			OriginalSize: isize,
+		})
+		if err := writeMerged(doc, title, isize, len(parts)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+// Shrink a single part of a series. Returns the part and the size of the clipping.
+func shrinkPart(filename string) (util.SeriesPart, int64, error) {
+	fmt.Fprintf(os.Stderr, "shrinking %s...\n", filename)
+	file, err := os.Open(filename)
+	if err != nil {
+		return util.SeriesPart{}, 0, err
+	}
+	defer func() { _ = file.Close() }()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return util.SeriesPart{}, 0, fmt.Errorf("reading failed: %w", err)
+	}
+	doc, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return util.SeriesPart{}, 0, fmt.Errorf("parsing HTML failed: %w", err)
+	}
+	info, err := newDocument(doc, filename)
+	if err != nil {
+		return util.SeriesPart{}, 0, err
+	}
+	_, strategy, found := util.LocateContent(doc, info.strategies)
+	if !found {
+		return util.SeriesPart{}, 0, fmt.Errorf("no main content found in %s", filename)
+	}
+	info.strategy = strategy.Name
+	applyCleanupPasses(doc, info)
+	part := util.SeriesPart{
+		Title:  info.title,
+		Source: sourceOf(info),
+		Doc:    doc,
+	}
+	if info.metadata.Published != nil {
+		part.Published = *info.metadata.Published
+	}
+	return part, int64(len(content)), nil
+}
+
+// Render the merged document and write it to the output file.
+func writeMerged(doc *html.Node, title string, isize int64, parts int) error {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		return fmt.Errorf("rendering HTML failed: %w", err)
+	}
+	ofile, ofileName, err := createOutputFile(outfilePath, outfileName, title)
+	if err != nil {
+		return fmt.Errorf("creating the output file failed: %w", err)
+	}
+	defer func() { _ = ofile.Close() }()
+	if _, err = ofile.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("writing the output file failed: %w", err)
+	}
+	fmt.Printf("merged %d parts into %s, reducing the size from %s to %s\n",
+		parts, ofileName, formatSize(isize), formatSize(int64(buf.Len())))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+
+	mergeCmd.PersistentFlags().StringVar(&outfileName, "outfile", "", "The name of the output file, by default the title of the series.")
+	mergeCmd.PersistentFlags().StringVar(&outfilePath, "outpath", "./", "The path where the output file shall be written.")
+	mergeCmd.PersistentFlags().StringVar(&mergeOrder, "order", orderByDate, "Order of the parts: date (publish date) or args (as given).")
+	mergeCmd.PersistentFlags().StringVar(&mergeTitle, "title", "", "Title of the merged document, by default derived from the titles of the parts.")
+}
@@ -0,0 +1,330 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/stbraun/shrinkr/util"
+	"golang.org/x/net/html"
+)
+
+// Information about the document being processed, shared by the cleanup passes.
+type document struct {
+	filename string
+	title    string
+	metadata util.Metadata
+	// Size and hex encoded SHA-256 of the original clipping.
+	size   int64
+	sha256 string
+	// Profile of the site the document was clipped from, nil if unknown.
+	profile *util.Profile
+	// Strategies locating the main content and the name of the one which found it.
+	strategies []util.Strategy
+	strategy   string
+	// Words, reading time and language of the output.
+	reading util.ReadingInfo
+	// Problems found with the content, e.g. a paywall.
+	flags []util.ContentFlag
+	// Counters contributed by the passes, added to the run statistics.
+	counters map[string]int64
+}
+
+// Add n to the named counter of the document.
+func (d *document) count(name string, n int) {
+	if n == 0 {
+		return
+	}
+	if d.counters == nil {
+		d.counters = map[string]int64{}
+	}
+	d.counters[name] += int64(n)
+}
+
+// A cleanup pass modifies a document in place.
+type cleanupPass struct {
+	name        string
+	description string
+	apply       func(*html.Node, *document)
+	// Reports whether the pass is switched on; nil means always.
+	enabled func() bool
+	// Applied instead of apply when estimating the savings of the pass, for passes
+	// whose flags select what they do; nil means apply.
+	estimate func(*html.Node, *document)
+}
+
+// Saving a single pass achieves if applied on its own.
+type passSaving struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Saved       int64  `json:"saved"`
+}
+
+// The cleanup passes in the order they are applied by the shrink command.
+var cleanupPasses = []cleanupPass{
+	{
+		name:        "prune-siblings",
+		description: "Remove everything around the main content.",
+		apply:       pruneSiblings,
+	},
+	{
+		name:        "profile-cleanup",
+		description: "Remove site specific boilerplate from the main content.",
+		apply:       removeBoilerplate,
+	},
+	{
+		name:        "normalize-images",
+		description: "Reduce responsive and lazy-loaded images to a plain <img>.",
+		apply:       normalizeImages,
+		enabled:     func() bool { return !keepImages },
+	},
+	{
+		name:        "replace-embeds",
+		description: "Replace embedded iframes by links or code.",
+		apply:       replaceEmbeds,
+	},
+	{
+		name:        "resolve-urls",
+		description: "Make relative URLs absolute.",
+		apply:       resolveURLs,
+	},
+	{
+		name:        "rewrite-head",
+		description: "Replace the <head> by a minimal one.",
+		apply:       rewriteHead,
+		enabled:     func() bool { return !keepHead },
+	},
+	{
+		name:        "clean-links",
+		description: "Unwrap redirect links and strip tracking parameters.",
+		apply:       cleanLinks,
+		enabled:     func() bool { return !keepLinks },
+	},
+	{
+		name:        "toc",
+		description: "Insert a table of contents linking to the headings.",
+		apply:       insertTOC,
+		enabled:     func() bool { return withTOC },
+	},
+	{
+		name:        "cleanup-icons",
+		description: "Remove or deduplicate inline SVG icons.",
+		apply:       cleanupIcons,
+		enabled:     func() bool { return iconAction != util.IconsKeep },
+		estimate:    estimateIconCleanup,
+	},
+	{
+		name:        "drop-fonts",
+		description: "Remove web fonts embedded as data URLs.",
+		apply:       removeEmbeddedFonts,
+		enabled:     func() bool { return dropFonts },
+	},
+	{
+		name:        "simplify-structure",
+		description: "Unwrap redundant wrappers and remove empty elements.",
+		apply:       simplifyStructure,
+		enabled:     func() bool { return !keepStructure },
+	},
+	{
+		name:        "prune-css",
+		description: "Remove style rules matching nothing in the output.",
+		apply:       pruneCSS,
+		enabled:     func() bool { return keepStyles && !keepUnusedCSS },
+	},
+}
+
+// Apply all enabled cleanup passes to the document.
+// Returns the bytes removed by each pass applied.
+func applyCleanupPasses(doc *html.Node, info *document) map[string]int64 {
+	removed := map[string]int64{}
+	size := util.RenderedSize(doc)
+	for _, p := range cleanupPasses {
+		if p.enabled == nil || p.enabled() {
+			p.apply(doc, info)
+			newSize := util.RenderedSize(doc)
+			removed[p.name] = size - newSize
+			size = newSize
+		}
+	}
+	return removed
+}
+
+// The elements holding the main content: the one located by the strategies or, with
+// --all-articles and the article strategy, all top-level articles.
+func contentRoots(doc *html.Node, info *document) []*html.Node {
+	root, strategy, found := util.LocateContent(doc, info.strategies)
+	switch {
+	case !found:
+		return nil
+	case allArticles && strategy.Name == "article":
+		return util.Articles(doc)
+	}
+	return []*html.Node{root}
+}
+
+func pruneSiblings(doc *html.Node, info *document) {
+	switch roots := contentRoots(doc, info); len(roots) {
+	case 0:
+	case 1:
+		util.PruneAround(roots[0])
+	default:
+		util.PruneAroundAll(roots)
+	}
+}
+
+func removeBoilerplate(doc *html.Node, info *document) {
+	if info.profile == nil {
+		return
+	}
+	for _, root := range contentRoots(doc, info) {
+		info.count("boilerplate elements removed", util.ApplyRemovals(root, info.profile.Removals))
+	}
+}
+
+func rewriteHead(doc *html.Node, info *document) {
+	provenance := provenanceOf(info)
+	util.RewriteHead(doc, util.HeadOptions{
+		Allowlist:  headAllowlist,
+		KeepStyles: keepStyles,
+		Title:      info.title,
+		Provenance: &provenance,
+	})
+}
+
+func normalizeImages(doc *html.Node, info *document) {
+	info.count("images normalized", util.NormalizeImages(doc, util.ImagePolicy{Width: imageWidth}))
+}
+
+func replaceEmbeds(doc *html.Node, info *document) {
+	info.count("embeds replaced", util.ReplaceEmbeds(doc, embedActions))
+}
+
+func resolveURLs(doc *html.Node, info *document) {
+	base := util.DocumentBaseURL(doc, baseURL, info.metadata.CanonicalURL)
+	info.count("URLs resolved", util.ResolveURLs(doc, base))
+}
+
+func cleanLinks(doc *html.Node, info *document) {
+	counts := util.RewriteLinks(doc, trackingParams)
+	info.count("links unwrapped", counts.Unwrapped)
+	info.count("links stripped of tracking parameters", counts.Stripped)
+	info.count("links normalized", counts.Normalized)
+}
+
+func insertTOC(doc *html.Node, info *document) {
+	if body := util.FindElement(doc, "body"); body != nil {
+		info.count("headings listed in tables of contents", util.InsertTOC(doc, body))
+	}
+}
+
+func cleanupIcons(doc *html.Node, info *document) {
+	counts := util.CleanupIcons(doc, iconAction)
+	info.count("SVG icons removed", counts.Removed)
+	info.count("SVG icons deduplicated", counts.Deduplicated)
+}
+
+// Estimate cleanup-icons by deduplicating, the action which loses nothing.
+func estimateIconCleanup(doc *html.Node, info *document) {
+	util.CleanupIcons(doc, util.IconsDedupe)
+}
+
+func removeEmbeddedFonts(doc *html.Node, info *document) {
+	counts := util.RemoveEmbeddedFonts(doc)
+	info.count("embedded fonts removed", counts.RulesRemoved)
+	info.count("font bytes removed", counts.BytesRemoved)
+}
+
+func simplifyStructure(doc *html.Node, info *document) {
+	body := util.FindElement(doc, "body")
+	if body == nil {
+		return
+	}
+	var opts util.SimplifyOptions
+	if !keepStyles {
+		// Without style sheets classes carry no meaning; inline styles still apply,
+		// e.g. to hide an element.
+		opts.IgnoredAttributes = []string{"class"}
+	}
+	counts := util.Simplify(body, opts)
+	info.count("wrappers unwrapped", counts.Unwrapped)
+	info.count("empty elements removed", counts.Removed)
+}
+
+func pruneCSS(doc *html.Node, info *document) {
+	counts := util.PruneCSS(doc)
+	info.count("unused CSS rules removed", counts.RulesRemoved)
+	info.count("CSS bytes removed", counts.BytesRemoved)
+}
+
+// The element holding the text kept in the shrinked document: the content root or,
+// with several, the <body>.
+func keptRoot(doc *html.Node, info *document) *html.Node {
+	if roots := contentRoots(doc, info); len(roots) == 1 {
+		return roots[0]
+	}
+	if body := util.FindElement(doc, "body"); body != nil {
+		return body
+	}
+	return doc
+}
+
+// Measure the text kept in the shrinked document.
+func measureReading(doc *html.Node, info *document) util.ReadingInfo {
+	return util.MeasureReading(keptRoot(doc, info))
+}
+
+// Describe how the output for the document is produced.
+func provenanceOf(info *document) util.Provenance {
+	return util.Provenance{
+		Version:        rootCmd.Version,
+		Source:         sourceOf(info),
+		Processed:      time.Now(),
+		OriginalSize:   info.size,
+		OriginalSHA256: info.sha256,
+	}
+}
+
+// Determine what each cleanup pass would save if applied on its own to the document.
+// The document itself is left untouched.
+func estimatePassSavings(doc *html.Node, info *document) []passSaving {
+	size := util.RenderedSize(doc)
+	var savings []passSaving
+	for _, p := range cleanupPasses {
+		clone := util.CloneDocument(doc)
+		if p.estimate != nil {
+			p.estimate(clone, info)
+		} else {
+			p.apply(clone, info)
+		}
+		savings = append(savings, passSaving{Name: p.name, Description: p.description, Saved: size - util.RenderedSize(clone)})
+	}
+	return savings
+}
+
+// The canonical URL of the document or, if unknown, the name of the input file.
+func sourceOf(info *document) string {
+	if info.metadata.CanonicalURL != "" {
+		return info.metadata.CanonicalURL
+	}
+	return filepath.Base(info.filename)
+}
@@ -24,14 +24,20 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/stbraun/shrinkr/util"
+	"golang.org/x/net/html"
 )
 
 var (
-	cfgFile string
-	Verbose bool
+	cfgFile   string
+	Verbose   bool
+	unitsName string
+	sizeUnits util.SizeUnits
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -44,6 +50,11 @@ Shrinkr looks for an HTML element of type <article> and tries to remove all
 its siblings`,
 	Args:    cobra.NoArgs,
 	Version: "1.0.0",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		sizeUnits, err = util.ParseSizeUnits(unitsName)
+		return err
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -60,6 +71,52 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.shrinkr.yaml)")
 	rootCmd.PersistentFlags().BoolVar(&Verbose, "verbose", false, "Provide more information.")
+	rootCmd.PersistentFlags().StringVar(&unitsName, "units", "iec", "Units used to report sizes: iec (KiB, MiB, ...) or si (kB, MB, ...).")
+	rootCmd.PersistentFlags().StringSlice("strategy", []string{"article"}, "Extraction strategies used to locate the main content, tried in order.")
+	cobra.CheckErr(viper.BindPFlag("strategy", rootCmd.PersistentFlags().Lookup("strategy")))
+	rootCmd.PersistentFlags().String("profile", "auto", "Site profile: auto to detect it, none or one of "+strings.Join(util.ProfileNames(), ", ")+".")
+	cobra.CheckErr(viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile")))
+}
+
+// Determine the site profile configured for the document.
+// Returns nil if no profile is used.
+func selectProfile(doc *html.Node) (*util.Profile, error) {
+	switch name := viper.GetString("profile"); name {
+	case "", "none":
+		return nil, nil
+	case "auto":
+		if p, ok := util.DetectProfile(doc); ok {
+			return &p, nil
+		}
+		return nil, nil
+	default:
+		p, err := util.LookupProfile(name)
+		if err != nil {
+			return nil, err
+		}
+		return &p, nil
+	}
+}
+
+// Determine the strategies locating the main content: those of the profile
+// followed by the configured ones.
+func contentStrategies(profile *util.Profile) ([]util.Strategy, error) {
+	names := viper.GetStringSlice("strategy")
+	if profile != nil {
+		names = append(append([]string(nil), profile.Strategies...), names...)
+	}
+	var unique []string
+	for _, name := range names {
+		if !slices.Contains(unique, name) {
+			unique = append(unique, name)
+		}
+	}
+	return util.LookupStrategies(unique)
+}
+
+// Format a size in the units selected on the command line.
+func formatSize(size int64) string {
+	return util.FormatSize(size, sizeUnits)
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -22,10 +22,15 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/stbraun/shrinkr/util"
@@ -37,6 +42,30 @@ var (
 	outfilePath      string
 	stats            *util.Stats
 	doNotReportStats bool
+	strictMode       bool
+	keepHead         bool
+	force            bool
+	writeSidecar     bool
+	keepLinks        bool
+	baseURL          string
+	keepImages       bool
+	imageWidth       int
+	embedSettings    []string
+	embedActions     = util.DefaultEmbedActions()
+	trackingParams   []string
+	keepStyles       bool
+	keepStructure    bool
+	keepUnusedCSS    bool
+	dropFonts        bool
+	iconSetting      string
+	iconAction       = util.IconsKeep
+	allArticles      bool
+	splitArticles    bool
+	withTOC          bool
+	minWords         int
+	quarantinePath   string
+	headAllowlist    []string
+	validationLimits = util.DefaultValidationLimits()
 )
 
 // shrinkCmd represents the shrink command
@@ -48,6 +77,22 @@ In many cases references to other articles and other kind of overhead can be fou
 These artifacts may consume much more memory and disk space than the article.  
 Removing them can therefore shrink the size of the file quite a bit.`,
 	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		if allArticles && splitArticles {
+			return fmt.Errorf("--all-articles and --split exclude each other")
+		}
+		if writeSidecar && outfileName != "" && util.SidecarName(outfileName) == outfileName {
+			return fmt.Errorf("--sidecar would replace the output %s by its sidecar", outfileName)
+		}
+		if embedActions, err = util.ParseEmbedActions(embedSettings); err != nil {
+			return err
+		}
+		if iconAction, err = util.ParseIconAction(iconSetting); err != nil {
+			return err
+		}
+		return setupKeywords()
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		stats = util.NewStats()
 		stats.Start()
@@ -59,13 +104,18 @@ Removing them can therefore shrink the size of the file quite a bit.`,
 			listFilesToProcess(files)
 		}
 		for _, filename := range files {
-			if err = processFile(filename); err != nil {
-				fmt.Fprintf(os.Stderr, "Processing %s failed with %s.", filename, err)
+			started := time.Now()
+			if err = processFile(filename, started); err != nil {
+				stats.AddFailure(filename, err, time.Since(started))
+				fmt.Fprintf(os.Stderr, "Processing %s failed with %s.\n", filename, err)
 			}
 		}
+		if keywordLimit > 0 {
+			tagOutputs()
+		}
 		stats.Stop()
 		if !doNotReportStats {
-			reportStatistics(*stats)
+			reportStatistics(stats.Snapshot())
 		}
 	},
 }
@@ -78,43 +128,211 @@ func listFilesToProcess(files []string) {
 	fmt.Println("----------------")
 }
 
-func reportStatistics(stats util.Stats) {
+func reportStatistics(snap util.StatsSnapshot) {
 	fmt.Printf("\n----------\nStatistics\n----------\n")
 	fmt.Printf("%d articles were processed in %dms\nreducing the cumulated size by %s from %s to %s\n",
-		stats.Count(),
-		stats.ElapsedTime(),
-		util.FormatFileSize(stats.SizeReducedBy()),
-		util.FormatFileSize(stats.CumulatedSizesOfOriginalFiles()),
-		util.FormatFileSize(stats.CumulatedSizesOfShrinkedFiles()))
+		snap.Processed,
+		snap.ElapsedMs,
+		formatSize(snap.SizeReducedBy),
+		formatSize(snap.OriginalSize),
+		formatSize(snap.ShrinkedSize))
+	if snap.Failed > 0 || snap.Skipped > 0 || snap.Suspicious > 0 {
+		fmt.Printf("%d failed, %d skipped, %d suspicious\n", snap.Failed, snap.Skipped, snap.Suspicious)
+	}
+	if snap.Processed > 0 {
+		fmt.Printf("compression ratio: best %.1f%%, median %.1f%%, worst %.1f%%\n",
+			snap.BestRatio*100, snap.MedianRatio*100, snap.WorstRatio*100)
+		fmt.Printf("throughput: best %s/s, median %s/s, worst %s/s\n",
+			formatSize(int64(snap.BestThroughput)),
+			formatSize(int64(snap.MedianThroughput)),
+			formatSize(int64(snap.WorstThroughput)))
+	}
+	if snap.Words > 0 {
+		fmt.Printf("%d words, about %d minutes of reading\n", snap.Words, snap.ReadingMinutes)
+	}
+	if len(snap.Languages) > 0 {
+		langs := make([]string, 0, len(snap.Languages))
+		for lang, n := range snap.Languages {
+			langs = append(langs, fmt.Sprintf("%s %d", lang, n))
+		}
+		sort.Strings(langs)
+		fmt.Printf("languages: %s\n", strings.Join(langs, ", "))
+	}
+	names := make([]string, 0, len(snap.Counters))
+	for name := range snap.Counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s: %d\n", name, snap.Counters[name])
+	}
 	fmt.Println("----------")
 }
 
 // Shrink the given file and write to output file.
-func processFile(filename string) error {
+// The start time is used to record the processing duration in the statistics.
+func processFile(filename string, started time.Time) error {
 	fmt.Fprintf(os.Stderr, "shrinking %s...\n", filename)
 	file := util.OpenFile(filename)
 	defer func() { _ = file.Close() }()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("reading failed: %w", err)
+	}
 
-	doc, err := html.Parse(file)
+	doc, err := html.Parse(bytes.NewReader(content))
 	if err != nil {
 		return fmt.Errorf("parsing HTML failed: %w", err)
 	}
-	if !util.HasArticleElement(doc) {
-		return fmt.Errorf("no <article> element in %s", filename)
+	previous, shrunk := util.ReadProvenance(doc)
+	if shrunk && !force {
+		reason := "already shrunk by shrinkr " + previous.Version
+		fmt.Fprintf(os.Stderr, "skipping %s: %s\n", filename, reason)
+		stats.AddSkip(filename, reason)
+		return nil
+	}
+	if !shrunk {
+		previous = util.Provenance{OriginalSize: int64(len(content)), OriginalSHA256: fmt.Sprintf("%x", sha256.Sum256(content))}
+	}
+	articles := util.Articles(doc)
+	if !splitArticles || len(articles) < 2 {
+		return shrinkDocument(doc, filename, int64(len(content)), previous, 0, started)
+	}
+	// each part accounts for a share of the clipping proportional to its article
+	var sizes []int64
+	var total int64
+	for _, a := range articles {
+		sizes = append(sizes, util.RenderedSize(a))
+		total += sizes[len(sizes)-1]
 	}
-	title := util.LookupTitle(doc)
+	for i := range articles {
+		share := int64(len(content)) / int64(len(articles))
+		if total > 0 {
+			share = int64(len(content)) * sizes[i] / total
+		}
+		// each part keeps one of the articles only
+		part := util.CloneDocument(doc)
+		for j, a := range util.Articles(part) {
+			if j != i {
+				a.Parent.RemoveChild(a)
+			}
+		}
+		if err = shrinkDocument(part, filename, share, previous, i+1, started); err != nil {
+			return fmt.Errorf("article %d: %w", i+1, err)
+		}
+		started = time.Now()
+	}
+	return nil
+}
 
-	ofile, ofileName, err := createOutputFile(outfilePath, outfileName, title)
+// Shrink the parsed document of isize bytes and write the output file. The original
+// clipping is described by its provenance. Parts of a split document are numbered
+// from 1, else part is 0; their size is the share of the clipping they account for.
+func shrinkDocument(doc *html.Node, filename string, isize int64, original util.Provenance, part int, started time.Time) error {
+	info, err := newDocument(doc, filename)
 	if err != nil {
-		return fmt.Errorf("creating the output file failed: %w", err)
+		return err
+	}
+	root, strategy, found := util.LocateContent(doc, info.strategies)
+	if !found {
+		return fmt.Errorf("no main content found in %s", filename)
 	}
+	info.strategy = strategy.Name
+	if util.DetectPaywall(doc, root) {
+		info.flags = append(info.flags, util.FlagPaywalled)
+	}
+	info.size, info.sha256 = original.OriginalSize, original.OriginalSHA256
+	name, outName := filename, outfileName
+	if part > 0 {
+		name = fmt.Sprintf("%s#%d", filename, part)
+		if heading := util.ArticleHeading(util.LargestArticle(doc)); heading != "" {
+			info.title = heading
+		} else {
+			info.title = fmt.Sprintf("%s (%d)", info.title, part)
+		}
+		// articles may share a heading, the number of the part keeps the names apart
+		if outName != "" {
+			ext := filepath.Ext(outName)
+			outName = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(outName, ext), part, ext)
+		} else {
+			outName = fmt.Sprintf("%s-%d.html", sanitizeFilename(info.title), part)
+		}
+	}
+	baseline := util.MeasureBaseline(withoutBoilerplate(doc, info), isize, info.strategies)
+	if part > 0 {
+		// the output of a part keeps the page around the article, it may well exceed
+		// the share of the clipping the part accounts for
+		baseline.Size = 0
+	}
+	before := util.AnalyzeSizes(doc, info.strategies)
 
-	shrinkDocument(doc)
-	err = html.Render(ofile, doc)
-	if err != nil {
+	removedByPass := applyCleanupPasses(doc, info)
+	if keepHead {
+		util.AddProvenance(doc, provenanceOf(info))
+	}
+	info.reading = measureReading(doc, info)
+	util.AddReadingInfo(doc, info.reading)
+	if info.reading.Words < minWords {
+		info.flags = append(info.flags, util.FlagNearEmpty)
+	}
+	util.AddContentFlags(doc, info.flags)
+	outPath := outfilePath
+	for _, flag := range info.flags {
+		fmt.Fprintf(os.Stderr, "warning: %s: flagged as %s\n", filename, flag)
+		info.count("articles flagged as "+string(flag), 1)
+		if quarantinePath != "" {
+			outPath = quarantinePath
+		}
+	}
+	var buf bytes.Buffer
+	if err = html.Render(&buf, doc); err != nil {
 		return fmt.Errorf("rendering HTML failed: %w", err)
 	}
-	stats.AddSizes(util.GetFileSize(filename), util.GetFileSize(ofileName))
+	issues := util.ValidateShrink(baseline, doc, int64(buf.Len()), validationLimits)
+	if len(issues) > 0 {
+		if strictMode {
+			return fmt.Errorf("suspicious result, not written: %s", strings.Join(issues, "; "))
+		}
+		for _, issue := range issues {
+			fmt.Fprintf(os.Stderr, "warning: %s: %s\n", filename, issue)
+		}
+	}
+
+	ofile, ofileName, err := createOutputFile(outPath, outName, info.title)
+	if err != nil {
+		return fmt.Errorf("creating the output file failed: %w", err)
+	}
+	defer func() { _ = ofile.Close() }()
+	if _, err = ofile.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("writing the output file failed: %w", err)
+	}
+	if writeSidecar {
+		sidecar := util.NewSidecar(util.SidecarInput{
+			Source:        info.filename,
+			Provenance:    provenanceOf(info),
+			Output:        ofileName,
+			OutputSize:    int64(buf.Len()),
+			Metadata:      info.metadata,
+			Strategy:      info.strategy,
+			Before:        before,
+			After:         util.AnalyzeSizes(doc, info.strategies),
+			RemovedByPass: removedByPass,
+			Reading:       info.reading,
+			Flags:         info.flags,
+			Warnings:      issues,
+		})
+		if err = util.WriteSidecar(sidecar); err != nil {
+			return fmt.Errorf("writing the sidecar failed: %w", err)
+		}
+	}
+	if keywordLimit > 0 {
+		collectKeywords(doc, info, name, ofileName)
+	}
+	for name, n := range info.counters {
+		stats.AddCounter(name, n)
+	}
+	stats.AddReading(info.reading)
+	stats.AddSuccess(name, isize, int64(buf.Len()), time.Since(started), issues...)
 	return nil
 }
 
@@ -123,10 +341,9 @@ func createOutputFile(outPath, outName, title string) (*os.File, string, error)
 	var ofileName string
 	util.CreateDirIfNotExist(outPath)
 	if len(outName) > 0 {
-		ofileName = filepath.Join(outfilePath, outName)
+		ofileName = filepath.Join(outPath, outName)
 	} else {
-		shortenedTitle := sanitizeFilename(shortenTitle(title))
-		ofileName = filepath.Join(outfilePath, shortenedTitle+".html")
+		ofileName = filepath.Join(outPath, sanitizeFilename(title)+".html")
 	}
 	fmt.Fprintf(os.Stderr, "writing %s...\n", ofileName)
 	ofile, err := os.Create(ofileName)
@@ -136,11 +353,49 @@ func createOutputFile(outPath, outName, title string) (*os.File, string, error)
 	return ofile, ofileName, nil
 }
 
-// Cut off trailing meta data and spurious sentences from given title.
-func shortenTitle(title string) string {
-	shortenedTitle, _, _ := strings.Cut(title, " |")         // cut off meta data
-	shortenedTitle, _, _ = strings.Cut(shortenedTitle, ". ") // cut off following sentence(s)
-	return shortenedTitle
+// A copy of the document without the boilerplate the profile removes deliberately,
+// so that validation does not count that text as lost.
+func withoutBoilerplate(doc *html.Node, info *document) *html.Node {
+	if info.profile == nil {
+		return doc
+	}
+	clone := util.CloneDocument(doc)
+	removeBoilerplate(clone, &document{profile: info.profile, strategies: info.strategies})
+	return clone
+}
+
+// Collect the information about the document needed by the cleanup passes.
+func newDocument(doc *html.Node, filename string) (*document, error) {
+	profile, err := selectProfile(doc)
+	if err != nil {
+		return nil, err
+	}
+	strategies, err := contentStrategies(profile)
+	if err != nil {
+		return nil, err
+	}
+	if Verbose && profile != nil {
+		fmt.Fprintf(os.Stderr, "using profile %s\n", profile.Name)
+	}
+	return &document{
+		filename:   filename,
+		title:      titleOf(doc, filename),
+		metadata:   util.ExtractMetadata(doc),
+		profile:    profile,
+		strategies: strategies,
+	}, nil
+}
+
+// Determine the title of the document.
+// Falls back to the name of the input file if the document has no title.
+func titleOf(doc *html.Node, filename string) string {
+	if resolved, ok := util.ResolveTitle(doc); ok {
+		if Verbose {
+			fmt.Fprintf(os.Stderr, "title taken from %s: %s\n", resolved.Source, resolved.Title)
+		}
+		return resolved.Title
+	}
+	return strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
 }
 
 // Replace invalid characterrs in file name.
@@ -153,41 +408,35 @@ func sanitizeFilename(name string) string {
 	return sanitized
 }
 
-// Remove undesired HTML nodes from the document.
-func shrinkDocument(rootNode *html.Node) {
-	body := util.LookupBody(rootNode)
-	var result bool = false
-	var nodesToBeRemoved []*html.Node
-	var lookupArticle func(*html.Node) bool
-	lookupArticle = func(n *html.Node) bool {
-		if n.Type == html.ElementNode && n.Data == "article" {
-			nodesToBeRemoved = util.ListSiblingsOfNode(n)
-			return true
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			if lookupArticle(c) {
-				nodesToBeRemoved = append(nodesToBeRemoved, util.ListSiblingsOfNode(c)...)
-				result = true
-			} else {
-				r := c
-				nodesToBeRemoved = append(nodesToBeRemoved, r)
-			}
-		}
-		for _, r := range nodesToBeRemoved {
-			p := r.Parent
-			if p != nil {
-				p.RemoveChild(r)
-			}
-		}
-		return result
-	}
-	lookupArticle(body)
-}
-
 func init() {
 	rootCmd.AddCommand(shrinkCmd)
 
 	shrinkCmd.PersistentFlags().StringVar(&outfileName, "outfile", "", "The name of the output file.")
 	shrinkCmd.PersistentFlags().StringVar(&outfilePath, "outpath", "./", "The path where the output file shall be written.")
+	shrinkCmd.PersistentFlags().StringVar(&quarantinePath, "quarantine", "", "The path where outputs flagged as paywalled or near-empty are written instead.")
+	shrinkCmd.PersistentFlags().IntVar(&minWords, "min-words", 50, "Articles with fewer words are flagged as near-empty.")
 	shrinkCmd.PersistentFlags().BoolVar(&doNotReportStats, "nostats", false, "Suppress reporting of statistics.")
+	shrinkCmd.PersistentFlags().BoolVar(&writeSidecar, "sidecar", false, "Write a JSON file describing each output next to it.")
+	shrinkCmd.PersistentFlags().BoolVar(&force, "force", false, "Shrink documents even if they were produced by shrinkr already.")
+	shrinkCmd.PersistentFlags().BoolVar(&allArticles, "all-articles", false, "Keep all articles of pages with several, not only the one with the most text.")
+	shrinkCmd.PersistentFlags().BoolVar(&splitArticles, "split", false, "Write each article of pages with several to its own output file.")
+	shrinkCmd.PersistentFlags().BoolVar(&withTOC, "toc", false, "Insert a table of contents of the h1 to h4 headings at the top of the output.")
+	shrinkCmd.PersistentFlags().BoolVar(&keepHead, "keep-head", false, "Keep the original <head> instead of rebuilding a minimal one.")
+	shrinkCmd.PersistentFlags().BoolVar(&keepStyles, "keep-styles", false, "Keep style sheets when rebuilding the <head>.")
+	shrinkCmd.PersistentFlags().BoolVar(&keepUnusedCSS, "keep-unused-css", false, "Keep style rules which match nothing in the output.")
+	shrinkCmd.PersistentFlags().StringSliceVar(&headAllowlist, "head-allow", util.DefaultHeadAllowlist, "Names of the meta tags kept when rebuilding the <head>.")
+	shrinkCmd.PersistentFlags().BoolVar(&keepImages, "keep-images", false, "Leave responsive and lazy-loaded images untouched.")
+	shrinkCmd.PersistentFlags().IntVar(&imageWidth, "image-width", 1000, "Preferred image width in pixels; 0 keeps the widest variant.")
+	shrinkCmd.PersistentFlags().StringSliceVar(&embedSettings, "embeds", nil, "Handling of embeds per provider as provider=action, e.g. youtube=remove.\nProviders: gist, twitter, youtube, vimeo, codepen, other. Actions: card, code (gist only), keep, remove.")
+	shrinkCmd.PersistentFlags().StringVar(&iconSetting, "svg-icons", string(util.IconsKeep), "Handling of small inline SVG icons: keep, remove or dedupe.")
+	shrinkCmd.PersistentFlags().BoolVar(&dropFonts, "drop-fonts", false, "Remove web fonts embedded in style sheets, falling back to system fonts.")
+	shrinkCmd.PersistentFlags().StringVar(&baseURL, "base-url", "", "URL relative links are resolved against, overriding <base href> and the canonical URL.")
+	shrinkCmd.PersistentFlags().BoolVar(&keepLinks, "keep-links", false, "Leave links untouched instead of removing tracking parameters.")
+	shrinkCmd.PersistentFlags().BoolVar(&keepStructure, "keep-structure", false, "Keep redundant wrappers and empty elements.")
+	shrinkCmd.PersistentFlags().StringSliceVar(&trackingParams, "tracking-params", util.DefaultTrackingParams, "Query parameters removed from links; a trailing * matches a prefix.")
+	shrinkCmd.PersistentFlags().IntVar(&keywordLimit, "keywords", 0, "Tag each output with up to this many keywords; 0 disables tagging.")
+	shrinkCmd.PersistentFlags().StringSliceVar(&stopwordFiles, "stopwords", nil, "Additional stopwords for keyword extraction as language=file, one word per line.")
+	shrinkCmd.PersistentFlags().BoolVar(&strictMode, "strict", false, "Refuse to write results which fail validation.")
+	shrinkCmd.PersistentFlags().Float64Var(&validationLimits.MinTextRatio, "min-text-ratio", validationLimits.MinTextRatio, "Minimum share of the article text which must be retained.")
+	shrinkCmd.PersistentFlags().Float64Var(&validationLimits.MaxSizeRatio, "max-size-ratio", validationLimits.MaxSizeRatio, "Output must be smaller than this share of the input size.")
 }
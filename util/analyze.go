@@ -0,0 +1,252 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Rendered sizes of the parts of a document in bytes.
+// Categories may overlap, e.g. a script inside the article is counted for both.
+type SizeBreakdown struct {
+	Total     int64 `json:"total"`
+	Head      int64 `json:"head"`
+	Scripts   int64 `json:"scripts"`
+	Styles    int64 `json:"styles"`
+	Images    int64 `json:"images"`
+	Article   int64 `json:"article"`
+	Removable int64 `json:"removableSiblings"`
+}
+
+// Element which may hold the main content of a document.
+type Candidate struct {
+	Path        string  `json:"path"`
+	TextLength  int     `json:"textLength"`
+	Paragraphs  int     `json:"paragraphs"`
+	LinkDensity float64 `json:"linkDensity"`
+	Score       float64 `json:"score"`
+}
+
+// Create an independent copy of the given document.
+func CloneDocument(doc *html.Node) *html.Node {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		panic(err)
+	}
+	clone, err := html.Parse(&buf)
+	if err != nil {
+		panic(err)
+	}
+	return clone
+}
+
+// Determine the rendered size of the given node in bytes.
+func RenderedSize(n *html.Node) int64 {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, n); err != nil {
+		return 0
+	}
+	return int64(buf.Len())
+}
+
+// Determine the rendered sizes of the parts of the given document. The main content
+// is located with the given strategies, the article strategy if none are given.
+func AnalyzeSizes(doc *html.Node, using []Strategy) SizeBreakdown {
+	sb := SizeBreakdown{Total: RenderedSize(doc)}
+	if head := FindElement(doc, "head"); head != nil {
+		sb.Head = RenderedSize(head)
+	}
+	if content := locateContent(doc, using); content != nil {
+		sb.Article = RenderedSize(content)
+		for _, n := range SiblingsAround(content) {
+			sb.Removable += RenderedSize(n)
+		}
+	}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script":
+				sb.Scripts += RenderedSize(n)
+				return
+			case "style":
+				sb.Styles += RenderedSize(n)
+				return
+			case "img", "picture", "svg":
+				sb.Images += RenderedSize(n)
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return sb
+}
+
+// Determine the nodes the sibling pruning would remove: the siblings of the main
+// content located with the given strategies and of each of its ancestors below <body>.
+func RemovableSiblings(doc *html.Node, using []Strategy) []*html.Node {
+	content := locateContent(doc, using)
+	if content == nil {
+		return nil
+	}
+	return SiblingsAround(content)
+}
+
+// Locate the main content with the given strategies, the article strategy if none
+// are given. Returns nil if it is not found.
+func locateContent(doc *html.Node, using []Strategy) *html.Node {
+	if len(using) == 0 {
+		using = []Strategy{strategies["article"]}
+	}
+	if content, _, found := LocateContent(doc, using); found {
+		return content
+	}
+	return nil
+}
+
+// Determine the siblings of the given node and of each of its ancestors below <body>.
+func SiblingsAround(root *html.Node) []*html.Node {
+	var removable []*html.Node
+	for n := root; n != nil && n.Parent != nil; n = n.Parent {
+		if n.Type == html.ElementNode && n.Data == "body" {
+			break
+		}
+		removable = append(removable, ListSiblingsOfNode(n)...)
+	}
+	return removable
+}
+
+// Remove everything around the given content root up to <body>.
+func PruneAround(root *html.Node) {
+	for _, n := range SiblingsAround(root) {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+// Score the elements of the given document by their likelihood of holding the main content.
+// Returns at most limit candidates, best first.
+func ContentCandidates(doc *html.Node, limit int) []Candidate {
+	var candidates []Candidate
+	for _, sn := range scoredNodes(doc) {
+		if limit > 0 && len(candidates) == limit {
+			break
+		}
+		candidates = append(candidates, sn.Candidate)
+	}
+	return candidates
+}
+
+// Candidate together with the element it describes.
+type scoredNode struct {
+	Candidate
+	node *html.Node
+}
+
+// Score all candidate elements of the document, best first.
+func scoredNodes(doc *html.Node) []scoredNode {
+	var scored []scoredNode
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "article", "main", "section", "div":
+				if c, ok := scoreCandidate(n); ok {
+					scored = append(scored, scoredNode{Candidate: c, node: n})
+				}
+			case "head", "script", "style", "nav", "footer", "header", "aside":
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
+	return scored
+}
+
+// Score a single element. Elements without text are no candidates.
+func scoreCandidate(n *html.Node) (Candidate, bool) {
+	textLen := TextLength(n)
+	if textLen == 0 {
+		return Candidate{}, false
+	}
+	linkLen := 0
+	paragraphs := 0
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.ElementNode {
+			switch c.Data {
+			case "a":
+				linkLen += TextLength(c)
+				return
+			case "p", "pre", "blockquote":
+				paragraphs++
+			}
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	density := float64(linkLen) / float64(textLen)
+	score := (float64(textLen)/100 + float64(paragraphs)*3) * (1 - density)
+	switch {
+	case n.Data == "article", n.Data == "main", GetAttribute(n, "role") == "main":
+		score *= 1.5
+	}
+	return Candidate{
+		Path:        NodePath(n),
+		TextLength:  textLen,
+		Paragraphs:  paragraphs,
+		LinkDensity: density,
+		Score:       score,
+	}, true
+}
+
+// Describe the position of a node in the tree, e.g. "html > body > div#main > article".
+func NodePath(n *html.Node) string {
+	var parts []string
+	for ; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		part := n.Data
+		if id := GetAttribute(n, "id"); id != "" {
+			part += "#" + id
+		} else if class := strings.Fields(GetAttribute(n, "class")); len(class) > 0 {
+			part += "." + class[0]
+		}
+		parts = append([]string{part}, parts...)
+	}
+	return strings.Join(parts, " > ")
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"testing"
+)
+
+const analyzeDoc = `<html><head><title>T</title><style>p{}</style></head><body>` +
+	`<div id="page"><nav><a href="/">Home</a></nav>` +
+	`<article><p>First paragraph of the article.</p><p>Second paragraph.</p><img src="a.png"></article>` +
+	`<div class="related"><a href="/x">Other story</a></div></div><footer>Footer</footer></body></html>`
+
+func TestAnalyzeSizes(t *testing.T) {
+	doc := mustParse(t, analyzeDoc)
+	sb := AnalyzeSizes(doc, nil)
+	article := FindElement(doc, "article")
+	if sb.Article != RenderedSize(article) {
+		t.Errorf("AnalyzeSizes() article = %d, want %d", sb.Article, RenderedSize(article))
+	}
+	if sb.Styles != int64(len("<style>p{}</style>")) {
+		t.Errorf("AnalyzeSizes() styles = %d", sb.Styles)
+	}
+	if sb.Images != int64(len(`<img src="a.png"/>`)) {
+		t.Errorf("AnalyzeSizes() images = %d", sb.Images)
+	}
+	if sb.Removable == 0 || sb.Removable >= sb.Total {
+		t.Errorf("AnalyzeSizes() removable = %d of %d", sb.Removable, sb.Total)
+	}
+}
+
+func TestAnalyzeSizes_largestArticle(t *testing.T) {
+	doc := mustParse(t, `<html><body><article><p>Teaser</p></article>`+
+		`<article><p>The article with much more text than the teaser before it.</p></article></body></html>`)
+	sb := AnalyzeSizes(doc, nil)
+	if want := RenderedSize(LargestArticle(doc)); sb.Article != want {
+		t.Errorf("AnalyzeSizes() article = %d, want %d", sb.Article, want)
+	}
+	if want := RenderedSize(FindElement(doc, "article")); sb.Removable != want {
+		t.Errorf("AnalyzeSizes() removable = %d, want %d", sb.Removable, want)
+	}
+}
+
+func TestRemovableSiblings(t *testing.T) {
+	doc := mustParse(t, analyzeDoc)
+	var tags []string
+	for _, n := range RemovableSiblings(doc, nil) {
+		tags = append(tags, n.Data)
+	}
+	want := []string{"nav", "div", "footer"}
+	if len(tags) != len(want) {
+		t.Fatalf("RemovableSiblings() = %v, want %v", tags, want)
+	}
+	for i := range want {
+		if tags[i] != want[i] {
+			t.Errorf("RemovableSiblings() = %v, want %v", tags, want)
+		}
+	}
+}
+
+func TestContentCandidates(t *testing.T) {
+	doc := mustParse(t, analyzeDoc)
+	candidates := ContentCandidates(doc, 2)
+	if len(candidates) != 2 {
+		t.Fatalf("ContentCandidates() returned %d candidates, want 2", len(candidates))
+	}
+	if want := "html > body > div#page > article"; candidates[0].Path != want {
+		t.Errorf("ContentCandidates() best = %q, want %q", candidates[0].Path, want)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Returns the <article> elements of the document which are not nested in another
+// <article>, in document order.
+func Articles(doc *html.Node) []*html.Node {
+	var articles []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "article" {
+			articles = append(articles, n)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return articles
+}
+
+// Returns the top-level <article> with the most text, the first one on a tie,
+// or nil if the document has no article.
+func LargestArticle(doc *html.Node) *html.Node {
+	var largest *html.Node
+	maxLen := -1
+	for _, a := range Articles(doc) {
+		if l := TextLength(a); l > maxLen {
+			largest, maxLen = a, l
+		}
+	}
+	return largest
+}
+
+// Remove everything in the <body> which is neither one of the given content roots,
+// part of one of them nor an ancestor of one of them.
+func PruneAroundAll(roots []*html.Node) {
+	keep := map[*html.Node]bool{}
+	ancestors := map[*html.Node]bool{}
+	var body *html.Node
+	for _, r := range roots {
+		keep[r] = true
+		for p := r.Parent; p != nil; p = p.Parent {
+			if p.Type == html.ElementNode && p.Data == "body" {
+				body = p
+				break
+			}
+			ancestors[p] = true
+		}
+	}
+	if body == nil {
+		return
+	}
+	var prune func(*html.Node)
+	prune = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			switch {
+			case keep[c]:
+			case ancestors[c]:
+				prune(c)
+			default:
+				n.RemoveChild(c)
+			}
+			c = next
+		}
+	}
+	prune(body)
+}
+
+// Returns the text of the first heading of the article, empty if there is none.
+func ArticleHeading(article *html.Node) string {
+	for _, tag := range []string{"h1", "h2", "h3"} {
+		if h := FindElement(article, tag); h != nil {
+			if text := strings.Join(strings.Fields(TextContent(h)), " "); text != "" {
+				return text
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"testing"
+)
+
+const listPage = `<html><head><title>List</title></head><body><nav>Menu</nav>` +
+	`<article id="a"><h2>First</h2><p>Short.</p></article>` +
+	`<aside>Ads</aside>` +
+	`<div><article id="b"><h1>Second</h1><p>The longest text of all articles on this page.</p>` +
+	`<article id="c"><h3>Related</h3></article></article></div>` +
+	`<footer>Footer</footer></body></html>`
+
+func TestArticles(t *testing.T) {
+	doc := mustParse(t, listPage)
+	articles := Articles(doc)
+	if len(articles) != 2 || GetAttribute(articles[0], "id") != "a" || GetAttribute(articles[1], "id") != "b" {
+		t.Fatalf("Articles() = %d articles, want a and b", len(articles))
+	}
+	if got := GetAttribute(LargestArticle(doc), "id"); got != "b" {
+		t.Errorf("LargestArticle() = %q, want b", got)
+	}
+	if LargestArticle(mustParse(t, "<p>No article</p>")) != nil {
+		t.Error("LargestArticle() found an article in a document without one")
+	}
+	if got := ArticleHeading(articles[0]); got != "First" {
+		t.Errorf("ArticleHeading() = %q, want First", got)
+	}
+	if got := ArticleHeading(articles[1]); got != "Second" {
+		t.Errorf("ArticleHeading() = %q, want Second", got)
+	}
+}
+
+func TestPruneAroundAll(t *testing.T) {
+	doc := mustParse(t, listPage)
+	PruneAroundAll(Articles(doc))
+	want := `<body><article id="a"><h2>First</h2><p>Short.</p></article>` +
+		`<div><article id="b"><h1>Second</h1><p>The longest text of all articles on this page.</p>` +
+		`<article id="c"><h3>Related</h3></article></article></div></body>`
+	if got := renderNode(t, FindElement(doc, "body")); got != want {
+		t.Errorf("PruneAroundAll() =\n%s\nwant\n%s", got, want)
+	}
+}
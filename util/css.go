@@ -0,0 +1,665 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Number of changes made by PruneCSS.
+type CSSCounts struct {
+	// Style rules, @font-face and @keyframes rules removed.
+	RulesRemoved int
+	// Bytes saved in the <style> elements.
+	BytesRemoved int
+}
+
+// A rule of a style sheet. Statements like @import are kept verbatim in prelude,
+// grouping rules like @media hold their rules in children.
+type cssRule struct {
+	prelude   string
+	block     string
+	statement bool
+	grouping  bool
+	children  []*cssRule
+}
+
+// At-rules containing further rules.
+var groupingAtRules = []string{"@media", "@supports", "@layer", "@container", "@document", "@-moz-document"}
+
+// Remove the rules of all <style> elements which are not used by the document: style
+// rules none of whose selectors matches an element, and @font-face and @keyframes rules
+// whose font family or animation is not referenced by the remaining rules.
+// Selectors which can not be evaluated, e.g. those with escapes or namespaces, are
+// considered to match. Pseudo-classes and pseudo-elements are ignored.
+func PruneCSS(doc *html.Node) CSSCounts {
+	var counts CSSCounts
+	var elements, styles []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			elements = append(elements, n)
+			if n.Data == "style" {
+				styles = append(styles, n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	m := &selectorMatcher{elements: elements, cache: map[string]bool{}}
+	sheets := make([][]*cssRule, len(styles))
+	usage := cssUsage{}
+	for _, n := range elements {
+		if style := GetAttribute(n, "style"); style != "" {
+			usage.add(style)
+		}
+	}
+	for i, s := range styles {
+		sheets[i] = m.pruneStyleRules(parseCSS(TextContentRaw(s)), &counts, &usage)
+	}
+	for i, s := range styles {
+		before := len(TextContentRaw(s))
+		css := renderCSS(pruneAtRules(sheets[i], &counts, usage))
+		for c := s.FirstChild; c != nil; c = s.FirstChild {
+			s.RemoveChild(c)
+		}
+		if css != "" {
+			s.AppendChild(&html.Node{Type: html.TextNode, Data: css})
+		}
+		counts.BytesRemoved += before - len(css)
+	}
+	return counts
+}
+
+// Remove @font-face rules of all <style> elements which embed the font as data URL, so
+// that the browser falls back to the next font of the family list or a system font.
+func RemoveEmbeddedFonts(doc *html.Node) CSSCounts {
+	var counts CSSCounts
+	for _, s := range findAll(doc, "style") {
+		css := TextContentRaw(s)
+		removed := counts.RulesRemoved
+		rules := dropEmbeddedFonts(parseCSS(css), &counts)
+		if counts.RulesRemoved == removed {
+			continue
+		}
+		pruned := renderCSS(rules)
+		for c := s.FirstChild; c != nil; c = s.FirstChild {
+			s.RemoveChild(c)
+		}
+		if pruned != "" {
+			s.AppendChild(&html.Node{Type: html.TextNode, Data: pruned})
+		}
+		counts.BytesRemoved += len(css) - len(pruned)
+	}
+	return counts
+}
+
+func dropEmbeddedFonts(rules []*cssRule, counts *CSSCounts) []*cssRule {
+	var kept []*cssRule
+	for _, r := range rules {
+		switch {
+		case r.grouping:
+			r.children = dropEmbeddedFonts(r.children, counts)
+			if len(r.children) == 0 {
+				continue
+			}
+		case atRuleName(r.prelude) == "@font-face":
+			if src, _ := declarationValue(r.block, "src"); strings.Contains(strings.ToLower(src), "data:") {
+				counts.RulesRemoved++
+				continue
+			}
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// Split a style sheet into its rules. Comments are dropped.
+func parseCSS(css string) []*cssRule {
+	var rules []*cssRule
+	i := 0
+	for i < len(css) {
+		i = skipCSSSpace(css, i)
+		if i >= len(css) {
+			break
+		}
+		end, delim := scanCSS(css, i, "{;")
+		prelude := strings.TrimSpace(stripCSSComments(css[i:end]))
+		switch {
+		case delim != '{':
+			if delim == ';' {
+				prelude += ";"
+			}
+			if prelude != "" {
+				rules = append(rules, &cssRule{prelude: prelude, statement: true})
+			}
+			i = end + 1
+		default:
+			close, _ := scanCSS(css, end+1, "}")
+			r := &cssRule{prelude: prelude, block: css[end+1 : min(close, len(css))]}
+			if isGroupingRule(prelude) {
+				r.grouping = true
+				r.children = parseCSS(r.block)
+			}
+			rules = append(rules, r)
+			i = close + 1
+		}
+	}
+	return rules
+}
+
+// Find the first of the delimiters at nesting depth zero, skipping strings and comments.
+// Returns the position and the delimiter found, or len(css) and 0.
+func scanCSS(css string, i int, delims string) (int, byte) {
+	depth := 0
+	for ; i < len(css); i++ {
+		c := css[i]
+		switch {
+		case c == '"' || c == '\'':
+			for i++; i < len(css) && css[i] != c; i++ {
+				if css[i] == '\\' {
+					i++
+				}
+			}
+		case c == '/' && strings.HasPrefix(css[i:], "/*"):
+			end := strings.Index(css[i+2:], "*/")
+			if end < 0 {
+				return len(css), 0
+			}
+			i += end + 3
+		case depth == 0 && strings.IndexByte(delims, c) >= 0:
+			return i, c
+		case c == '{' || c == '(' || c == '[':
+			depth++
+		case c == '}' || c == ')' || c == ']':
+			depth--
+		}
+	}
+	return len(css), 0
+}
+
+func skipCSSSpace(css string, i int) int {
+	for i < len(css) {
+		switch {
+		case strings.HasPrefix(css[i:], "/*"):
+			end := strings.Index(css[i+2:], "*/")
+			if end < 0 {
+				return len(css)
+			}
+			i += end + 4
+		case css[i] == ' ' || css[i] == '\t' || css[i] == '\n' || css[i] == '\r' || css[i] == '\f':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+func stripCSSComments(s string) string {
+	for {
+		start := strings.Index(s, "/*")
+		if start < 0 {
+			return s
+		}
+		end := strings.Index(s[start+2:], "*/")
+		if end < 0 {
+			return s[:start]
+		}
+		s = s[:start] + " " + s[start+end+4:]
+	}
+}
+
+func isGroupingRule(prelude string) bool {
+	name := atRuleName(prelude)
+	for _, g := range groupingAtRules {
+		if name == g {
+			return true
+		}
+	}
+	return false
+}
+
+// Name of the at-rule in lower case, empty for style rules.
+func atRuleName(prelude string) string {
+	if !strings.HasPrefix(prelude, "@") {
+		return ""
+	}
+	end := strings.IndexFunc(prelude, func(r rune) bool { return r == ' ' || r == '(' || r == '\n' || r == '\t' || r == '{' })
+	if end < 0 {
+		end = len(prelude)
+	}
+	return strings.ToLower(prelude[:end])
+}
+
+// Render the rules as a style sheet, one rule per line.
+func renderCSS(rules []*cssRule) string {
+	var b strings.Builder
+	for i, r := range rules {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		switch {
+		case r.statement:
+			b.WriteString(r.prelude)
+		case r.grouping:
+			b.WriteString(r.prelude + "{\n" + renderCSS(r.children) + "\n}")
+		default:
+			b.WriteString(r.prelude + "{" + r.block + "}")
+		}
+	}
+	return b.String()
+}
+
+// Font families and animation names referenced by declarations.
+type cssUsage struct {
+	fonts      []string
+	animations []string
+}
+
+// Record the fonts and animations used by a block of declarations.
+func (u *cssUsage) add(declarations string) {
+	for _, d := range strings.Split(stripCSSComments(declarations), ";") {
+		prop, value, ok := strings.Cut(d, ":")
+		if !ok {
+			continue
+		}
+		prop = strings.ToLower(strings.TrimSpace(prop))
+		value = strings.ToLower(value)
+		if strings.HasPrefix(prop, "--") {
+			// custom properties may be used as font family or animation by var()
+			u.fonts = append(u.fonts, value)
+			u.animations = append(u.animations, value)
+			continue
+		}
+		switch strings.TrimPrefix(strings.TrimPrefix(prop, "-webkit-"), "-moz-") {
+		case "font", "font-family":
+			u.fonts = append(u.fonts, value)
+		case "animation", "animation-name":
+			u.animations = append(u.animations, value)
+		}
+	}
+}
+
+func (u cssUsage) usesFont(family string) bool {
+	family = strings.ToLower(strings.Trim(strings.TrimSpace(family), `"'`))
+	for _, v := range u.fonts {
+		if strings.Contains(v, family) {
+			return true
+		}
+	}
+	return false
+}
+
+func (u cssUsage) usesAnimation(name string) bool {
+	name = strings.ToLower(strings.Trim(strings.TrimSpace(name), `"'`))
+	for _, v := range u.animations {
+		for _, token := range strings.FieldsFunc(v, func(r rune) bool { return r == ' ' || r == ',' || r == '"' || r == '\'' }) {
+			if token == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Drop style rules without matching selectors and record the usage of the remaining ones.
+func (m *selectorMatcher) pruneStyleRules(rules []*cssRule, counts *CSSCounts, usage *cssUsage) []*cssRule {
+	var kept []*cssRule
+	for _, r := range rules {
+		switch {
+		case r.statement:
+		case r.grouping:
+			r.children = m.pruneStyleRules(r.children, counts, usage)
+		case atRuleName(r.prelude) != "":
+			// @font-face, @keyframes and @page don't have selectors.
+		default:
+			var selectors []string
+			for _, s := range splitCSS(r.prelude, ',') {
+				if m.matches(strings.TrimSpace(s)) {
+					selectors = append(selectors, strings.TrimSpace(s))
+				}
+			}
+			if len(selectors) == 0 {
+				counts.RulesRemoved++
+				continue
+			}
+			if len(selectors) < len(splitCSS(r.prelude, ',')) {
+				r.prelude = strings.Join(selectors, ",")
+			}
+			usage.add(r.block)
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// Drop unused @font-face and @keyframes rules and grouping rules left empty.
+func pruneAtRules(rules []*cssRule, counts *CSSCounts, usage cssUsage) []*cssRule {
+	var kept []*cssRule
+	for _, r := range rules {
+		switch name := atRuleName(r.prelude); {
+		case r.statement:
+		case r.grouping:
+			r.children = pruneAtRules(r.children, counts, usage)
+			if len(r.children) == 0 {
+				continue
+			}
+		case name == "@font-face":
+			if family, ok := declarationValue(r.block, "font-family"); ok && !usage.usesFont(family) {
+				counts.RulesRemoved++
+				continue
+			}
+		case strings.HasSuffix(name, "keyframes"):
+			if !usage.usesAnimation(strings.TrimSpace(r.prelude[len(name):])) {
+				counts.RulesRemoved++
+				continue
+			}
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// Value of the named property in a block of declarations.
+func declarationValue(declarations, property string) (string, bool) {
+	for _, d := range strings.Split(stripCSSComments(declarations), ";") {
+		if prop, value, ok := strings.Cut(d, ":"); ok && strings.EqualFold(strings.TrimSpace(prop), property) {
+			return strings.TrimSpace(value), true
+		}
+	}
+	return "", false
+}
+
+// Split s at sep outside of brackets and strings.
+func splitCSS(s string, sep byte) []string {
+	var parts []string
+	for {
+		end, _ := scanCSS(s, 0, string(sep))
+		parts = append(parts, s[:end])
+		if end >= len(s) {
+			return parts
+		}
+		s = s[end+1:]
+	}
+}
+
+// Evaluates selectors against the elements of a document.
+type selectorMatcher struct {
+	elements []*html.Node
+	cache    map[string]bool
+}
+
+// Reports whether the selector matches any element. Selectors which can not be
+// evaluated are reported as matching.
+func (m *selectorMatcher) matches(selector string) bool {
+	if result, ok := m.cache[selector]; ok {
+		return result
+	}
+	result := true
+	if compounds, ok := parseSelector(selector); ok {
+		result = false
+		for _, n := range m.elements {
+			if matchSelector(compounds, len(compounds)-1, n) {
+				result = true
+				break
+			}
+		}
+	}
+	m.cache[selector] = result
+	return result
+}
+
+// A compound selector and the combinator relating it to the previous one.
+type compoundSelector struct {
+	combinator byte
+	tag        string
+	ids        []string
+	classes    []string
+	attributes []attributeSelector
+}
+
+type attributeSelector struct {
+	name, op, value string
+	ignoreCase      bool
+}
+
+func isIdentChar(c byte) bool {
+	return c == '-' || c == '_' || c >= 0x80 || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// Parse a complex selector. Reports false if it uses syntax not supported.
+func parseSelector(s string) ([]compoundSelector, bool) {
+	var compounds []compoundSelector
+	var combinator byte
+	i := 0
+	ident := func() string {
+		start := i
+		for i < len(s) && isIdentChar(s[i]) {
+			i++
+		}
+		return s[start:i]
+	}
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			if len(compounds) > 0 && combinator == 0 {
+				combinator = ' '
+			}
+			i++
+			continue
+		case c == '>' || c == '+' || c == '~':
+			if len(compounds) == 0 {
+				return nil, false
+			}
+			combinator = c
+			i++
+			continue
+		}
+		cs := compoundSelector{combinator: combinator}
+		combinator = 0
+		if len(compounds) == 0 {
+			cs.combinator = 0
+		}
+		if s[i] == '*' {
+			i++
+		} else {
+			cs.tag = strings.ToLower(ident())
+		}
+	compound:
+		for i < len(s) {
+			switch s[i] {
+			case '#':
+				i++
+				cs.ids = append(cs.ids, ident())
+			case '.':
+				i++
+				cs.classes = append(cs.classes, ident())
+			case '[':
+				end, _ := scanCSS(s, i+1, "]")
+				if end >= len(s) {
+					return nil, false
+				}
+				a, ok := parseAttributeSelector(s[i+1 : end])
+				if !ok {
+					return nil, false
+				}
+				cs.attributes = append(cs.attributes, a)
+				i = end + 1
+			case ':':
+				// Pseudo-classes and pseudo-elements don't restrict the match.
+				for i < len(s) && s[i] == ':' {
+					i++
+				}
+				ident()
+				if i < len(s) && s[i] == '(' {
+					end, _ := scanCSS(s, i+1, ")")
+					i = end + 1
+				}
+			case ' ', '\t', '\n', '\r', '\f', '>', '+', '~':
+				break compound
+			default:
+				return nil, false
+			}
+		}
+		for _, v := range append(cs.ids, cs.classes...) {
+			if v == "" {
+				return nil, false
+			}
+		}
+		compounds = append(compounds, cs)
+	}
+	return compounds, len(compounds) > 0 && combinator == 0
+}
+
+// Parse the inside of an attribute selector.
+func parseAttributeSelector(s string) (attributeSelector, bool) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, "=~|^$*")
+	if i < 0 {
+		return attributeSelector{name: strings.ToLower(s)}, s != "" && !strings.ContainsAny(s, " \\")
+	}
+	a := attributeSelector{name: strings.ToLower(strings.TrimSpace(s[:i]))}
+	if s[i] == '=' {
+		a.op = "="
+	} else if i+1 < len(s) && s[i+1] == '=' {
+		a.op = s[i : i+2]
+	} else {
+		return a, false
+	}
+	value := strings.TrimSpace(s[i+len(a.op):])
+	if strings.HasSuffix(value, " i") || strings.HasSuffix(value, " I") {
+		a.ignoreCase = true
+		value = strings.TrimSpace(value[:len(value)-2])
+	} else if strings.HasSuffix(value, " s") || strings.HasSuffix(value, " S") {
+		value = strings.TrimSpace(value[:len(value)-2])
+	}
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	a.value = value
+	return a, a.name != "" && !strings.ContainsAny(a.name+a.value, "\\|")
+}
+
+// Reports whether the compounds up to index i match the element n.
+func matchSelector(compounds []compoundSelector, i int, n *html.Node) bool {
+	if !matchCompound(compounds[i], n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch compounds[i].combinator {
+	case '>':
+		p := n.Parent
+		return p != nil && p.Type == html.ElementNode && matchSelector(compounds, i-1, p)
+	case '+':
+		p := previousElement(n)
+		return p != nil && matchSelector(compounds, i-1, p)
+	case '~':
+		for p := previousElement(n); p != nil; p = previousElement(p) {
+			if matchSelector(compounds, i-1, p) {
+				return true
+			}
+		}
+	default:
+		for p := n.Parent; p != nil && p.Type == html.ElementNode; p = p.Parent {
+			if matchSelector(compounds, i-1, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func previousElement(n *html.Node) *html.Node {
+	for p := n.PrevSibling; p != nil; p = p.PrevSibling {
+		if p.Type == html.ElementNode {
+			return p
+		}
+	}
+	return nil
+}
+
+func matchCompound(cs compoundSelector, n *html.Node) bool {
+	if n.Type != html.ElementNode || (cs.tag != "" && cs.tag != n.Data) {
+		return false
+	}
+	for _, id := range cs.ids {
+		if GetAttribute(n, "id") != id {
+			return false
+		}
+	}
+	classes := strings.Fields(GetAttribute(n, "class"))
+	for _, class := range cs.classes {
+		found := false
+		for _, c := range classes {
+			found = found || c == class
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, a := range cs.attributes {
+		if !matchAttribute(a, n) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAttribute(a attributeSelector, n *html.Node) bool {
+	if !hasAttribute(n, a.name) {
+		return false
+	}
+	value, expected := GetAttribute(n, a.name), a.value
+	if a.ignoreCase {
+		value, expected = strings.ToLower(value), strings.ToLower(expected)
+	}
+	switch a.op {
+	case "":
+		return true
+	case "=":
+		return value == expected
+	case "~=":
+		for _, f := range strings.Fields(value) {
+			if f == expected {
+				return true
+			}
+		}
+		return false
+	case "|=":
+		return value == expected || strings.HasPrefix(value, expected+"-")
+	case "^=":
+		return expected != "" && strings.HasPrefix(value, expected)
+	case "$=":
+		return expected != "" && strings.HasSuffix(value, expected)
+	case "*=":
+		return expected != "" && strings.Contains(value, expected)
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"testing"
+)
+
+func TestPruneCSS(t *testing.T) {
+	body := `<body><article class="post"><h1 id="title">T</h1><p lang="en-US">Text <a href="https://x.org">x</a></p></article></body>`
+	tests := []struct {
+		name    string
+		css     string
+		want    string
+		removed int
+	}{
+		{"unused rules",
+			`p{margin:0} .sidebar{float:left} nav a{color:red}`,
+			`p{margin:0}`, 2},
+		{"selector list reduced",
+			`h1, .footer, h2{font-size:2em}`,
+			`h1{font-size:2em}`, 0},
+		{"combinators",
+			`article > h1{a:1} h1 + p{b:2} h1 ~ p a{c:3} body > p{d:4} p + h1{e:5}`,
+			`article > h1{a:1}` + "\n" + `h1 + p{b:2}` + "\n" + `h1 ~ p a{c:3}`, 2},
+		{"attributes and pseudo-classes",
+			`a[href^="https"]:hover{a:1} [lang|=en]{b:2} a[href$=".pdf"]{c:3} #title::before{d:4} #other{e:5}`,
+			`a[href^="https"]:hover{a:1}` + "\n" + `[lang|=en]{b:2}` + "\n" + `#title::before{d:4}`, 2},
+		{"media queries",
+			`@media (max-width: 600px) { .sidebar{display:none} p{margin:1em} } @media print { nav{display:none} }`,
+			"@media (max-width: 600px){\np{margin:1em}\n}", 2},
+		{"fonts and keyframes",
+			`@font-face{font-family:"Used";src:url(a.woff2)} @font-face{font-family:Unused;src:url(b.woff2)}` +
+				`@keyframes fade{from{opacity:0}} @keyframes spin{to{transform:rotate(1turn)}}` +
+				`h1{font:bold 2em "Used", serif;animation:fade 1s} .nav{animation-name:spin}`,
+			`@font-face{font-family:"Used";src:url(a.woff2)}` + "\n" + `@keyframes fade{from{opacity:0}}` + "\n" +
+				`h1{font:bold 2em "Used", serif;animation:fade 1s}`, 3},
+		{"custom properties",
+			`:root{--font:"Inter";--fade:fade} @font-face{font-family:"Inter";src:url(i.woff2)} @keyframes fade{to{opacity:1}}` +
+				` p{font-family:var(--font);animation:var(--fade) 1s}`,
+			`:root{--font:"Inter";--fade:fade}` + "\n" + `@font-face{font-family:"Inter";src:url(i.woff2)}` + "\n" +
+				`@keyframes fade{to{opacity:1}}` + "\n" + `p{font-family:var(--font);animation:var(--fade) 1s}`, 0},
+		{"statements and comments kept",
+			`@charset "utf-8"; /* comment */ @import url(x.css); :root{--c:red} x\:y{a:1}`,
+			`@charset "utf-8";` + "\n" + `@import url(x.css);` + "\n" + `:root{--c:red}` + "\n" + `x\:y{a:1}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := mustParse(t, `<html><head><style>`+tt.css+`</style></head>`+body+`</html>`)
+			counts := PruneCSS(doc)
+			if got := TextContentRaw(FindElement(doc, "style")); got != tt.want {
+				t.Errorf("PruneCSS() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if counts.RulesRemoved != tt.removed {
+				t.Errorf("PruneCSS() removed %d rules, want %d", counts.RulesRemoved, tt.removed)
+			}
+			if want := len(tt.css) - len(tt.want); counts.BytesRemoved != want {
+				t.Errorf("PruneCSS() removed %d bytes, want %d", counts.BytesRemoved, want)
+			}
+		})
+	}
+}
+
+func TestRemoveEmbeddedFonts(t *testing.T) {
+	css := `@font-face{font-family:A;src:url(data:font/woff2;base64,d09GMgABAAAAA)}` +
+		` @font-face{font-family:B;src:url(b.woff2)} @media screen{@font-face{font-family:C;src:url("data:font/woff;base64,AAAA")}}` +
+		` body{font-family:A, B, sans-serif}`
+	doc := mustParse(t, `<html><head><style>`+css+`</style></head><body></body></html>`)
+	counts := RemoveEmbeddedFonts(doc)
+	want := `@font-face{font-family:B;src:url(b.woff2)}` + "\n" + `body{font-family:A, B, sans-serif}`
+	if got := TextContentRaw(FindElement(doc, "style")); got != want {
+		t.Errorf("RemoveEmbeddedFonts() =\n%s\nwant\n%s", got, want)
+	}
+	if counts.RulesRemoved != 2 || counts.BytesRemoved != len(css)-len(want) {
+		t.Errorf("RemoveEmbeddedFonts() counts = %+v", counts)
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// What to do with an embedded iframe.
+type EmbedAction string
+
+const (
+	// Replace the iframe by a link to the embedded content.
+	EmbedCard EmbedAction = "card"
+	// Replace the iframe by the code of the embedded gist if the page contains it, else by a card.
+	EmbedCode EmbedAction = "code"
+	// Leave the iframe as it is.
+	EmbedKeep EmbedAction = "keep"
+	// Drop the iframe.
+	EmbedRemove EmbedAction = "remove"
+)
+
+// Name used for iframes not belonging to a known provider.
+const OtherEmbeds = "other"
+
+// Embed providers recognized by the host of the embedded URL.
+var embedProviders = []struct {
+	name  string
+	label string
+	hosts []string
+}{
+	{"gist", "GitHub Gist", []string{"gist.github.com"}},
+	{"twitter", "Twitter", []string{"twitter.com", "platform.twitter.com", "x.com"}},
+	{"youtube", "YouTube", []string{"youtube.com", "www.youtube.com", "youtube-nocookie.com", "www.youtube-nocookie.com", "youtu.be"}},
+	{"vimeo", "Vimeo", []string{"vimeo.com", "player.vimeo.com"}},
+	{"codepen", "CodePen", []string{"codepen.io"}},
+}
+
+// Actions applied to embeds unless configured otherwise, by provider.
+func DefaultEmbedActions() map[string]EmbedAction {
+	return map[string]EmbedAction{
+		"gist":      EmbedCode,
+		"twitter":   EmbedCard,
+		"youtube":   EmbedCard,
+		"vimeo":     EmbedCard,
+		"codepen":   EmbedCard,
+		OtherEmbeds: EmbedCard,
+	}
+}
+
+// Parse settings of the form provider=action and apply them to the default actions.
+func ParseEmbedActions(specs []string) (map[string]EmbedAction, error) {
+	actions := DefaultEmbedActions()
+	for _, spec := range specs {
+		provider, action, ok := strings.Cut(strings.TrimSpace(spec), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid embed setting %q, expected provider=action", spec)
+		}
+		if _, known := actions[provider]; !known {
+			return nil, fmt.Errorf("unknown embed provider %q", provider)
+		}
+		switch a := EmbedAction(action); a {
+		case EmbedCard, EmbedKeep, EmbedRemove:
+			actions[provider] = a
+		case EmbedCode:
+			if provider != "gist" {
+				return nil, fmt.Errorf("action code is only supported for gists")
+			}
+			actions[provider] = a
+		default:
+			return nil, fmt.Errorf("unknown embed action %q", action)
+		}
+	}
+	return actions, nil
+}
+
+// Replace embedded iframes by static content according to the actions per provider.
+// Iframes without a source are left alone, as there is nothing to link to.
+// Returns the number of iframes replaced or removed.
+func ReplaceEmbeds(doc *html.Node, actions map[string]EmbedAction) int {
+	replaced := 0
+	for _, iframe := range findAll(doc, "iframe") {
+		src := strings.TrimSpace(GetAttribute(iframe, "src"))
+		if iframe.Parent == nil || src == "" {
+			continue
+		}
+		provider, label, target := identifyEmbed(src)
+		action, ok := actions[provider]
+		if !ok {
+			action = EmbedCard
+		}
+		var replacement *html.Node
+		switch action {
+		case EmbedKeep:
+			continue
+		case EmbedRemove:
+		case EmbedCode:
+			replacement = gistCode(doc, target)
+			if replacement == nil {
+				replacement = embedCard(iframe, label, target)
+			}
+		default:
+			replacement = embedCard(iframe, label, target)
+		}
+		if replacement != nil {
+			iframe.Parent.InsertBefore(replacement, iframe)
+		}
+		iframe.Parent.RemoveChild(iframe)
+		replaced++
+	}
+	return replaced
+}
+
+// Determine the provider of an embedded URL and the URL of the embedded content.
+// Embeds wrapped by embed.ly are unwrapped.
+func identifyEmbed(src string) (string, string, string) {
+	u, err := url.Parse(strings.TrimSpace(src))
+	if err != nil {
+		return OtherEmbeds, "", src
+	}
+	if strings.HasSuffix(u.Hostname(), "embedly.com") {
+		for _, key := range []string{"url", "src"} {
+			if inner := u.Query().Get(key); inner != "" {
+				if iu, err := url.Parse(inner); err == nil && iu.IsAbs() {
+					u = iu
+					break
+				}
+			}
+		}
+	}
+	target := u.String()
+	host := strings.ToLower(u.Hostname())
+	for _, p := range embedProviders {
+		for _, h := range p.hosts {
+			if host == h {
+				if p.name == "youtube" {
+					target = youtubeWatchURL(u)
+				}
+				return p.name, p.label, target
+			}
+		}
+	}
+	return OtherEmbeds, u.Hostname(), target
+}
+
+// Turn a YouTube embed URL into the URL of its watch page.
+func youtubeWatchURL(u *url.URL) string {
+	if id, ok := strings.CutPrefix(u.Path, "/embed/"); ok && id != "" {
+		return "https://www.youtube.com/watch?v=" + id
+	}
+	return u.String()
+}
+
+// A paragraph linking to the embedded content, titled like the iframe.
+func embedCard(iframe *html.Node, label, target string) *html.Node {
+	if target == "" {
+		return nil
+	}
+	title := strings.TrimSpace(GetAttribute(iframe, "title"))
+	if title == "" {
+		title = target
+	}
+	p := &html.Node{Type: html.ElementNode, Data: "p", DataAtom: atom.P,
+		Attr: []html.Attribute{{Key: "class", Val: "shrinkr-embed"}}}
+	a := &html.Node{Type: html.ElementNode, Data: "a", DataAtom: atom.A,
+		Attr: []html.Attribute{{Key: "href", Val: target}}}
+	a.AppendChild(&html.Node{Type: html.TextNode, Data: title})
+	p.AppendChild(a)
+	if label != "" {
+		p.AppendChild(&html.Node{Type: html.TextNode, Data: " (" + label + ")"})
+	}
+	return p
+}
+
+// The code of a gist rendered into the page, as a <pre> element.
+// Returns nil if the page does not contain the gist.
+func gistCode(doc *html.Node, gistURL string) *html.Node {
+	id := gistID(gistURL)
+	if id == "" {
+		return nil
+	}
+	var lines []string
+	for _, div := range findAll(doc, "div") {
+		if !strings.Contains(" "+GetAttribute(div, "class")+" ", " gist ") || !referencesGist(div, id) {
+			continue
+		}
+		for _, td := range findAll(div, "td") {
+			if strings.Contains(GetAttribute(td, "class"), "blob-code") {
+				lines = append(lines, TextContent(td))
+			}
+		}
+		if len(lines) > 0 && div.Parent != nil {
+			div.Parent.RemoveChild(div)
+		}
+		break
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	pre := &html.Node{Type: html.ElementNode, Data: "pre", DataAtom: atom.Pre}
+	code := &html.Node{Type: html.ElementNode, Data: "code", DataAtom: atom.Code}
+	code.AppendChild(&html.Node{Type: html.TextNode, Data: strings.Join(lines, "\n")})
+	pre.AppendChild(code)
+	return pre
+}
+
+// Extract the id of a gist from its URL, e.g. "abc123" from gist.github.com/user/abc123.js.
+func gistID(gistURL string) string {
+	u, err := url.Parse(gistURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	return strings.TrimSuffix(parts[len(parts)-1], ".js")
+}
+
+// Reports whether the rendered gist links to the gist with the given id.
+func referencesGist(n *html.Node, id string) bool {
+	for _, a := range findAll(n, "a") {
+		if strings.Contains(GetAttribute(a, "href"), id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"testing"
+)
+
+func TestReplaceEmbeds(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		actions []string
+		want    string
+	}{
+		{"youtube card",
+			`<iframe src="https://www.youtube.com/embed/abc123" title="A talk"></iframe>`,
+			nil,
+			`<p class="shrinkr-embed"><a href="https://www.youtube.com/watch?v=abc123">A talk</a> (YouTube)</p>`},
+		{"embedly wrapped tweet",
+			`<iframe src="https://cdn.embedly.com/widgets/media.html?url=https%3A%2F%2Ftwitter.com%2Fgolang%2Fstatus%2F1"></iframe>`,
+			nil,
+			`<p class="shrinkr-embed"><a href="https://twitter.com/golang/status/1">https://twitter.com/golang/status/1</a> (Twitter)</p>`},
+		{"gist code",
+			`<iframe src="https://gist.github.com/jane/f00ba4.js"></iframe>` +
+				`<div class="gist"><table><tr><td class="blob-code">func main() {</td></tr><tr><td class="blob-code">}</td></tr></table>` +
+				`<a href="https://gist.github.com/jane/f00ba4/raw">view raw</a></div>`,
+			nil,
+			"<pre><code>func main() {\n}</code></pre>"},
+		{"gist without code",
+			`<iframe src="https://gist.github.com/jane/f00ba4.js" title="main.go"></iframe>`,
+			nil,
+			`<p class="shrinkr-embed"><a href="https://gist.github.com/jane/f00ba4.js">main.go</a> (GitHub Gist)</p>`},
+		{"gist rendered without code lines",
+			`<iframe src="https://gist.github.com/jane/f00ba4.js" title="main.go"></iframe>` +
+				`<div class="gist"><a href="https://gist.github.com/jane/f00ba4/raw">view raw</a></div>`,
+			nil,
+			`<p class="shrinkr-embed"><a href="https://gist.github.com/jane/f00ba4.js">main.go</a> (GitHub Gist)</p>` +
+				`<div class="gist"><a href="https://gist.github.com/jane/f00ba4/raw">view raw</a></div>`},
+		{"removed",
+			`<iframe src="https://codepen.io/jane/embed/xyz"></iframe>`,
+			[]string{"codepen=remove"},
+			``},
+		{"kept",
+			`<iframe src="https://player.vimeo.com/video/1"></iframe>`,
+			[]string{"vimeo=keep"},
+			`<iframe src="https://player.vimeo.com/video/1"></iframe>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, err := ParseEmbedActions(tt.actions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc := mustParse(t, "<body>"+tt.doc+"</body>")
+			ReplaceEmbeds(doc, actions)
+			if got, want := renderNode(t, FindElement(doc, "body")), "<body>"+tt.want+"</body>"; got != want {
+				t.Errorf("ReplaceEmbeds() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestReplaceEmbeds_NoSource(t *testing.T) {
+	const src = `<iframe></iframe><iframe src=" "></iframe>`
+	doc := mustParse(t, "<body>"+src+"</body>")
+	if got := ReplaceEmbeds(doc, nil); got != 0 {
+		t.Errorf("ReplaceEmbeds() = %d, want 0", got)
+	}
+	if got, want := renderNode(t, FindElement(doc, "body")), "<body>"+src+"</body>"; got != want {
+		t.Errorf("ReplaceEmbeds() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseEmbedActions_Invalid(t *testing.T) {
+	for _, spec := range []string{"youtube", "myspace=card", "youtube=code", "youtube=explode"} {
+		if _, err := ParseEmbedActions([]string{spec}); err == nil {
+			t.Errorf("ParseEmbedActions(%q) expected an error", spec)
+		}
+	}
+}
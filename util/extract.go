@@ -0,0 +1,135 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// An extraction strategy locates the element holding the main content of a document.
+type Strategy struct {
+	Name        string
+	Description string
+	// Returns the content root or nil if the strategy does not apply to the document.
+	Locate func(doc *html.Node) *html.Node
+}
+
+var strategies = map[string]Strategy{}
+
+// Make a strategy available by its name.
+// Panics if a strategy with the same name is registered already.
+func RegisterStrategy(s Strategy) {
+	if _, ok := strategies[s.Name]; ok {
+		panic("strategy registered twice: " + s.Name)
+	}
+	strategies[s.Name] = s
+}
+
+// Look up a registered strategy by its name.
+func LookupStrategy(name string) (Strategy, error) {
+	s, ok := strategies[strings.TrimSpace(name)]
+	if !ok {
+		return Strategy{}, fmt.Errorf("unknown extraction strategy %q, available: %s",
+			name, strings.Join(StrategyNames(), ", "))
+	}
+	return s, nil
+}
+
+// Look up a list of strategies given by their names.
+func LookupStrategies(names []string) ([]Strategy, error) {
+	var result []Strategy
+	for _, name := range names {
+		s, err := LookupStrategy(name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// Returns the names of all registered strategies in alphabetical order.
+func StrategyNames() []string {
+	var names []string
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Try the given strategies in order and return the first content root found.
+// The boolean result is false if none of the strategies applies.
+func LocateContent(doc *html.Node, candidates []Strategy) (*html.Node, Strategy, bool) {
+	for _, s := range candidates {
+		if n := s.Locate(doc); n != nil {
+			return n, s, true
+		}
+	}
+	return nil, Strategy{}, false
+}
+
+// Look for an element with role="main".
+func findMainRole(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode && GetAttribute(n, "role") == "main" {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findMainRole(c); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func init() {
+	RegisterStrategy(Strategy{
+		Name:        "article",
+		Description: "The <article> element with the most text.",
+		Locate:      LargestArticle,
+	})
+	RegisterStrategy(Strategy{
+		Name:        "main",
+		Description: "The <main> element or the element with role=\"main\".",
+		Locate: func(doc *html.Node) *html.Node {
+			if n := FindElement(doc, "main"); n != nil {
+				return n
+			}
+			return findMainRole(doc)
+		},
+	})
+	RegisterStrategy(Strategy{
+		Name:        "score",
+		Description: "The element scoring best as content root.",
+		Locate: func(doc *html.Node) *html.Node {
+			candidates := scoredNodes(doc)
+			if len(candidates) == 0 {
+				return nil
+			}
+			return candidates[0].node
+		},
+	})
+}
@@ -0,0 +1,67 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"testing"
+)
+
+func TestLocateContent(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        string
+		strategies []string
+		wantFound  bool
+		wantName   string
+		wantTag    string
+	}{
+		{"article", `<body><div><article><p>Text</p></article></div></body>`, []string{"article"}, true, "article", "article"},
+		{"main element", `<body><main><p>Text</p></main></body>`, []string{"article", "main"}, true, "main", "main"},
+		{"main role", `<body><div role="main"><p>Text</p></div></body>`, []string{"main"}, true, "main", "div"},
+		{"score", `<body><div><p>Some longer text.</p></div></body>`, []string{"article", "score"}, true, "score", "div"},
+		{"nothing", `<body><p>Text</p></body>`, []string{"article", "main"}, false, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategies, err := LookupStrategies(tt.strategies)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n, s, found := LocateContent(mustParse(t, tt.doc), strategies)
+			if found != tt.wantFound {
+				t.Fatalf("LocateContent() found = %v, want %v", found, tt.wantFound)
+			}
+			if !found {
+				return
+			}
+			if s.Name != tt.wantName || n.Data != tt.wantTag {
+				t.Errorf("LocateContent() = %s/%s, want %s/%s", s.Name, n.Data, tt.wantName, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestLookupStrategy_Unknown(t *testing.T) {
+	if _, err := LookupStrategy("nonsense"); err == nil {
+		t.Error("LookupStrategy() expected an error for an unknown strategy")
+	}
+}
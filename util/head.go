@@ -0,0 +1,217 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Meta tags kept in a rewritten <head> unless configured otherwise.
+var DefaultHeadAllowlist = []string{
+	"viewport",
+	"author",
+	"og:title",
+	"og:description",
+	"og:url",
+	"og:image",
+	"og:site_name",
+	"article:published_time",
+	"article:modified_time",
+	"article:author",
+	"article:tag",
+}
+
+// Options controlling how the <head> is rewritten.
+type HeadOptions struct {
+	// Names or properties of the meta tags to keep besides the description.
+	Allowlist []string
+	// Keep <style> elements and stylesheet links.
+	KeepStyles bool
+	// Title to use if the document has no <title>.
+	Title string
+	// Provenance written into the head; nothing is written if nil.
+	Provenance *Provenance
+}
+
+// Information on how a document was produced by shrinkr.
+type Provenance struct {
+	Version      string
+	Source       string
+	Processed    time.Time
+	OriginalSize int64
+	// Hex encoded SHA-256 of the original document.
+	OriginalSHA256 string
+}
+
+// Prefix of the generator meta tag marking documents produced by shrinkr.
+const generatorPrefix = "shrinkr"
+
+// Replace the <head> of the document by a minimal one. Only the charset, title,
+// canonical link, description and allowlisted meta tags are kept.
+func RewriteHead(doc *html.Node, opts HeadOptions) {
+	head := FindElement(doc, "head")
+	if head == nil {
+		return
+	}
+	allowed := map[string]bool{"description": true}
+	for _, name := range opts.Allowlist {
+		allowed[strings.ToLower(name)] = true
+	}
+
+	var title *html.Node
+	var kept []*html.Node
+	for c := head.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		switch c.Data {
+		case "title":
+			if title == nil {
+				title = c
+			}
+		case "meta":
+			if GetAttribute(c, "charset") != "" {
+				continue // a fresh charset declaration is added below
+			}
+			name := GetAttribute(c, "name")
+			if name == "" {
+				name = GetAttribute(c, "property")
+			}
+			if allowed[strings.ToLower(name)] {
+				kept = append(kept, c)
+			}
+		case "link":
+			rel := strings.ToLower(GetAttribute(c, "rel"))
+			if rel == "canonical" || (opts.KeepStyles && rel == "stylesheet") {
+				kept = append(kept, c)
+			}
+		case "style":
+			if opts.KeepStyles {
+				kept = append(kept, c)
+			}
+		}
+	}
+	if title == nil && opts.Title != "" {
+		title = &html.Node{Type: html.ElementNode, Data: "title", DataAtom: atom.Title}
+		title.AppendChild(&html.Node{Type: html.TextNode, Data: opts.Title})
+	}
+
+	for c := head.FirstChild; c != nil; c = head.FirstChild {
+		head.RemoveChild(c)
+	}
+	head.AppendChild(newMeta("charset", "utf-8"))
+	if title != nil {
+		head.AppendChild(title)
+	}
+	for _, n := range kept {
+		head.AppendChild(n)
+	}
+	if opts.Provenance != nil {
+		AddProvenance(doc, *opts.Provenance)
+	}
+}
+
+// Write the provenance into the <head> of the document, replacing any existing one.
+func AddProvenance(doc *html.Node, p Provenance) {
+	head := FindElement(doc, "head")
+	if head == nil {
+		return
+	}
+	for c := head.FirstChild; c != nil; {
+		next := c.NextSibling
+		if isProvenanceNode(c) {
+			head.RemoveChild(c)
+		}
+		c = next
+	}
+	for _, n := range provenanceNodes(p) {
+		head.AppendChild(n)
+	}
+}
+
+// Read the provenance of a document produced by shrinkr.
+// The boolean result is false if the document was not produced by shrinkr.
+func ReadProvenance(doc *html.Node) (Provenance, bool) {
+	var version string
+	found := false
+	for _, generator := range MetaContents(doc, "generator") {
+		if version, found = strings.CutPrefix(generator, generatorPrefix+" "); found {
+			break
+		}
+	}
+	if !found {
+		return Provenance{}, false
+	}
+	p := Provenance{
+		Version:        version,
+		Source:         MetaContent(doc, "shrinkr:source"),
+		OriginalSHA256: MetaContent(doc, "shrinkr:original-sha256"),
+	}
+	p.OriginalSize, _ = strconv.ParseInt(MetaContent(doc, "shrinkr:original-size"), 10, 64)
+	p.Processed, _ = time.Parse(time.RFC3339, MetaContent(doc, "shrinkr:processed"))
+	return p, true
+}
+
+// Reports whether the node is one of the meta tags written by AddProvenance.
+func isProvenanceNode(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.Data != "meta" {
+		return false
+	}
+	name := GetAttribute(n, "name")
+	return strings.HasPrefix(name, "shrinkr:") ||
+		(name == "generator" && strings.HasPrefix(GetAttribute(n, "content"), generatorPrefix))
+}
+
+// Meta tags describing the provenance of a document.
+func provenanceNodes(p Provenance) []*html.Node {
+	nodes := []*html.Node{newNamedMeta("generator", generatorPrefix+" "+p.Version)}
+	if p.OriginalSize > 0 {
+		nodes = append(nodes, newNamedMeta("shrinkr:original-size", strconv.FormatInt(p.OriginalSize, 10)))
+	}
+	if p.OriginalSHA256 != "" {
+		nodes = append(nodes, newNamedMeta("shrinkr:original-sha256", p.OriginalSHA256))
+	}
+	if p.Source != "" {
+		nodes = append(nodes, newNamedMeta("shrinkr:source", p.Source))
+	}
+	if !p.Processed.IsZero() {
+		nodes = append(nodes, newNamedMeta("shrinkr:processed", p.Processed.UTC().Format(time.RFC3339)))
+	}
+	return nodes
+}
+
+// Create a <meta> element with a single attribute.
+func newMeta(key, val string) *html.Node {
+	return &html.Node{Type: html.ElementNode, Data: "meta", DataAtom: atom.Meta,
+		Attr: []html.Attribute{{Key: key, Val: val}}}
+}
+
+// Create a <meta name="..." content="..."> element.
+func newNamedMeta(name, content string) *html.Node {
+	return &html.Node{Type: html.ElementNode, Data: "meta", DataAtom: atom.Meta,
+		Attr: []html.Attribute{{Key: "name", Val: name}, {Key: "content", Val: content}}}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const headDoc = `<html><head><meta charset="iso-8859-1"><title>The title</title>
+<meta name="description" content="Desc"><meta property="og:title" content="OG">
+<meta property="fb:app_id" content="123"><link rel="preload" href="font.woff">
+<link rel="canonical" href="https://example.com/post"><link rel="stylesheet" href="s.css">
+<script src="app.js"></script><style>p{}</style></head><body><p>Text</p></body></html>`
+
+func renderHead(t *testing.T, doc *html.Node) string {
+	t.Helper()
+	return renderNode(t, FindElement(doc, "head"))
+}
+
+func TestRewriteHead(t *testing.T) {
+	doc := mustParse(t, headDoc)
+	RewriteHead(doc, HeadOptions{
+		Allowlist:  []string{"og:title"},
+		Provenance: &Provenance{Version: "1.2.3", Source: "https://example.com/post", Processed: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+	})
+	want := `<head><meta charset="utf-8"/><title>The title</title>` +
+		`<meta name="description" content="Desc"/><meta property="og:title" content="OG"/>` +
+		`<link rel="canonical" href="https://example.com/post"/>` +
+		`<meta name="generator" content="shrinkr 1.2.3"/><meta name="shrinkr:source" content="https://example.com/post"/>` +
+		`<meta name="shrinkr:processed" content="2024-05-01T12:00:00Z"/></head>`
+	if got := renderHead(t, doc); got != want {
+		t.Errorf("RewriteHead() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRewriteHead_KeepStylesAndAddTitle(t *testing.T) {
+	doc := mustParse(t, `<html><head><link rel="stylesheet" href="s.css"><style>p{}</style><script></script></head><body></body></html>`)
+	RewriteHead(doc, HeadOptions{KeepStyles: true, Title: "Fallback"})
+	got := renderHead(t, doc)
+	for _, want := range []string{"<title>Fallback</title>", `<link rel="stylesheet" href="s.css"/>`, "<style>p{}</style>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RewriteHead() = %s, missing %s", got, want)
+		}
+	}
+	if strings.Contains(got, "script") {
+		t.Errorf("RewriteHead() = %s, script not removed", got)
+	}
+}
+
+func TestProvenance_RoundTrip(t *testing.T) {
+	doc := mustParse(t, `<html><head><meta name="generator" content="WordPress 6.0"><title>T</title></head><body></body></html>`)
+	if _, ok := ReadProvenance(doc); ok {
+		t.Fatal("ReadProvenance() found provenance in an original document")
+	}
+	want := Provenance{
+		Version:        "1.2.3",
+		Source:         "a.html",
+		Processed:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		OriginalSize:   4711,
+		OriginalSHA256: "abc123",
+	}
+	AddProvenance(doc, Provenance{Version: "1.0.0"})
+	AddProvenance(doc, want)
+	got, ok := ReadProvenance(mustParse(t, renderHead(t, doc)))
+	if !ok {
+		t.Fatal("ReadProvenance() did not find provenance")
+	}
+	if got != want {
+		t.Errorf("ReadProvenance() = %+v, want %+v", got, want)
+	}
+	if n := strings.Count(renderHead(t, doc), `name="generator"`); n != 2 {
+		t.Errorf("AddProvenance() left %d generator tags, want the original one and its own", n)
+	}
+}
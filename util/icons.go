@@ -0,0 +1,192 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// What to do with inline SVG icons.
+type IconAction string
+
+const (
+	// Leave the icons as they are.
+	IconsKeep IconAction = "keep"
+	// Drop the icons.
+	IconsRemove IconAction = "remove"
+	// Define repeated icons once and refer to the definition by <use>.
+	IconsDedupe IconAction = "dedupe"
+)
+
+// Inline SVGs at most this many pixels wide and high are considered icons.
+const maxIconSize = 64
+
+// Number of changes made by CleanupIcons.
+type IconCounts struct {
+	Removed      int
+	Deduplicated int
+}
+
+// Parse the action applied to inline SVG icons.
+func ParseIconAction(s string) (IconAction, error) {
+	switch a := IconAction(strings.ToLower(strings.TrimSpace(s))); a {
+	case IconsKeep, IconsRemove, IconsDedupe:
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown SVG icon action %q, expected keep, remove or dedupe", s)
+}
+
+// Remove or deduplicate the inline SVG icons of the document. Deduplicated icons are
+// moved as <symbol> into a hidden sprite at the start of the body, each occurrence
+// keeps its own <svg> element with a <use> referring to the symbol.
+func CleanupIcons(doc *html.Node, action IconAction) IconCounts {
+	var counts IconCounts
+	var icons []*html.Node
+	for _, svg := range findAll(doc, "svg") {
+		if isIcon(svg) {
+			icons = append(icons, svg)
+		}
+	}
+	switch action {
+	case IconsRemove:
+		for _, svg := range icons {
+			svg.Parent.RemoveChild(svg)
+			counts.Removed++
+		}
+	case IconsDedupe:
+		counts.Deduplicated = dedupeIcons(doc, icons)
+	}
+	return counts
+}
+
+// Reports whether the element is a small, top-level inline SVG.
+func isIcon(svg *html.Node) bool {
+	if svg.Parent == nil {
+		return false
+	}
+	for p := svg.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "svg" {
+			return false
+		}
+	}
+	width, wok := svgLength(GetAttribute(svg, "width"))
+	height, hok := svgLength(GetAttribute(svg, "height"))
+	if box := strings.Fields(strings.ReplaceAll(GetAttribute(svg, "viewBox"), ",", " ")); len(box) == 4 {
+		if !wok {
+			width, wok = svgLength(box[2])
+		}
+		if !hok {
+			height, hok = svgLength(box[3])
+		}
+	}
+	return wok && hok && width <= maxIconSize && height <= maxIconSize
+}
+
+// Parse a length given in pixels.
+func svgLength(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "px"), 64)
+	return v, err == nil
+}
+
+// Replace the content of icons occurring more than once by a reference to a symbol.
+// Returns the number of icons replaced.
+func dedupeIcons(doc *html.Node, icons []*html.Node) int {
+	var keys []string
+	groups := map[string][]*html.Node{}
+	for _, svg := range icons {
+		var buf bytes.Buffer
+		for c := svg.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "use" {
+				buf.Reset()
+				break
+			}
+			_ = html.Render(&buf, c)
+		}
+		if buf.Len() == 0 {
+			continue
+		}
+		key := GetAttribute(svg, "viewBox") + "\x00" + buf.String()
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], svg)
+	}
+
+	used := map[string]bool{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if id := GetAttribute(n, "id"); id != "" {
+			used[id] = true
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	var sprite *html.Node
+	replaced, symbols := 0, 0
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+		if sprite == nil {
+			sprite = &html.Node{Type: html.ElementNode, Data: "svg", Namespace: "svg",
+				Attr: []html.Attribute{{Key: "style", Val: "display:none"}}}
+			parent := FindElement(doc, "body")
+			if parent == nil {
+				parent = group[0].Parent
+			}
+			parent.InsertBefore(sprite, parent.FirstChild)
+		}
+		symbols++
+		id := fmt.Sprintf("shrinkr-icon-%d", symbols)
+		for used[id] {
+			symbols++
+			id = fmt.Sprintf("shrinkr-icon-%d", symbols)
+		}
+		symbol := &html.Node{Type: html.ElementNode, Data: "symbol", Namespace: "svg",
+			Attr: []html.Attribute{{Key: "id", Val: id}}}
+		if box := GetAttribute(group[0], "viewBox"); box != "" {
+			symbol.Attr = append(symbol.Attr, html.Attribute{Key: "viewBox", Val: box})
+		}
+		for c := group[0].FirstChild; c != nil; c = group[0].FirstChild {
+			group[0].RemoveChild(c)
+			symbol.AppendChild(c)
+		}
+		sprite.AppendChild(symbol)
+		for _, svg := range group {
+			for c := svg.FirstChild; c != nil; c = svg.FirstChild {
+				svg.RemoveChild(c)
+			}
+			svg.AppendChild(&html.Node{Type: html.ElementNode, Data: "use", Namespace: "svg",
+				Attr: []html.Attribute{{Key: "href", Val: "#" + id}}})
+			replaced++
+		}
+	}
+	return replaced
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	starIcon = `<svg width="16" height="16" viewBox="0 0 16 16"><path d="M8 0l2 6h6l-5 4 2 6-5-4-5 4 2-6-5-4h6z"></path></svg>`
+	diagram  = `<svg viewBox="0 0 800 600"><rect width="800" height="600"></rect></svg>`
+)
+
+func TestCleanupIcons(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		action IconAction
+		want   string
+		counts IconCounts
+	}{
+		{"keep",
+			`<button>` + starIcon + `</button>`,
+			IconsKeep,
+			`<button>` + starIcon + `</button>`,
+			IconCounts{}},
+		{"remove",
+			`<button>` + starIcon + `Star</button>` + diagram,
+			IconsRemove,
+			`<button>Star</button>` + diagram,
+			IconCounts{Removed: 1}},
+		{"dedupe",
+			`<p>` + starIcon + `</p><p>` + starIcon + `</p>` + `<svg viewBox="0 0 24 24"><circle r="4"></circle></svg>`,
+			IconsDedupe,
+			`<svg style="display:none"><symbol id="shrinkr-icon-1" viewBox="0 0 16 16"><path d="M8 0l2 6h6l-5 4 2 6-5-4-5 4 2-6-5-4h6z"></path></symbol></svg>` +
+				`<p><svg width="16" height="16" viewBox="0 0 16 16"><use href="#shrinkr-icon-1"></use></svg></p>` +
+				`<p><svg width="16" height="16" viewBox="0 0 16 16"><use href="#shrinkr-icon-1"></use></svg></p>` +
+				`<svg viewBox="0 0 24 24"><circle r="4"></circle></svg>`,
+			IconCounts{Deduplicated: 2}},
+		{"dedupe skipping used ids",
+			`<p id="shrinkr-icon-1">` + starIcon + `</p><p>` + starIcon + `</p>`,
+			IconsDedupe,
+			`<svg style="display:none"><symbol id="shrinkr-icon-2" viewBox="0 0 16 16"><path d="M8 0l2 6h6l-5 4 2 6-5-4-5 4 2-6-5-4h6z"></path></symbol></svg>` +
+				`<p id="shrinkr-icon-1"><svg width="16" height="16" viewBox="0 0 16 16"><use href="#shrinkr-icon-2"></use></svg></p>` +
+				`<p><svg width="16" height="16" viewBox="0 0 16 16"><use href="#shrinkr-icon-2"></use></svg></p>`,
+			IconCounts{Deduplicated: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := mustParse(t, "<body>"+tt.doc+"</body>")
+			counts := CleanupIcons(doc, tt.action)
+			if got, want := renderNode(t, FindElement(doc, "body")), "<body>"+tt.want+"</body>"; got != want {
+				t.Errorf("CleanupIcons() =\n%s\nwant\n%s", got, want)
+			}
+			if counts != tt.counts {
+				t.Errorf("CleanupIcons() counts = %+v, want %+v", counts, tt.counts)
+			}
+		})
+	}
+}
+
+func TestParseIconAction(t *testing.T) {
+	if a, err := ParseIconAction("Dedupe"); err != nil || a != IconsDedupe {
+		t.Errorf("ParseIconAction(Dedupe) = %q, %v", a, err)
+	}
+	if _, err := ParseIconAction("hide"); err == nil || !strings.Contains(err.Error(), "hide") {
+		t.Errorf("ParseIconAction(hide) error = %v", err)
+	}
+}
@@ -0,0 +1,344 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Policy choosing one variant of a responsive image.
+type ImagePolicy struct {
+	// Preferred width in pixels. The smallest variant at least this wide is chosen,
+	// or the widest one if none is wide enough. 0 chooses the widest variant.
+	Width int
+}
+
+// Replace lazy-load placeholders by their <noscript> fallbacks, <picture> elements by
+// a plain <img> and reduce srcset attributes to a single source.
+// Returns the number of images changed.
+func NormalizeImages(doc *html.Node, policy ImagePolicy) int {
+	changed := resolveNoscriptImages(doc)
+	for _, p := range findAll(doc, "picture") {
+		replacePicture(p, policy)
+		changed++
+	}
+	for _, img := range findAll(doc, "img") {
+		if reduceImage(img, policy) {
+			changed++
+		}
+	}
+	return changed
+}
+
+// Collect all elements with the given tag in document order.
+func findAll(n *html.Node, tag string) []*html.Node {
+	var found []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == tag {
+			found = append(found, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return found
+}
+
+// Replace <noscript> elements holding an image by that image and drop the
+// lazy-load placeholders for it next to it. Returns the number of images resolved.
+func resolveNoscriptImages(doc *html.Node) int {
+	resolved := 0
+	for _, ns := range findAll(doc, "noscript") {
+		img := noscriptImage(ns)
+		if img == nil || ns.Parent == nil {
+			continue
+		}
+		parent := ns.Parent
+		files := imageFiles(img)
+		for c := parent.FirstChild; c != nil; {
+			next := c.NextSibling
+			if isLazyPlaceholder(c, files) {
+				parent.RemoveChild(c)
+			}
+			c = next
+		}
+		parent.InsertBefore(img, ns)
+		parent.RemoveChild(ns)
+		resolved++
+	}
+	return resolved
+}
+
+// The <img> inside a <noscript> element or nil. With scripting enabled the
+// parser keeps the content of <noscript> as text, so it is parsed here.
+func noscriptImage(ns *html.Node) *html.Node {
+	if img := FindElement(ns, "img"); img != nil {
+		ns.RemoveChild(img)
+		return img
+	}
+	raw := TextContentRaw(ns)
+	if !strings.Contains(raw, "<img") {
+		return nil
+	}
+	context := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(raw), context)
+	if err != nil {
+		return nil
+	}
+	for _, n := range nodes {
+		if img := FindElement(n, "img"); img != nil {
+			if img.Parent != nil {
+				img.Parent.RemoveChild(img)
+			}
+			return img
+		}
+	}
+	return nil
+}
+
+// Reports whether the node is a lazy-load placeholder for the image stored in one
+// of the given files: a canvas, which shows nothing without JavaScript, or an image
+// without a file of its own or referring to one of the files, e.g. a blurred thumbnail
+// of the same file or an image waiting for its source to be set by JavaScript.
+func isLazyPlaceholder(n *html.Node, files map[string]bool) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	switch n.Data {
+	case "canvas":
+		return true
+	case "img":
+		own := imageFiles(n)
+		if len(own) == 0 {
+			return true
+		}
+		for f := range own {
+			if files[f] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Names of the files an image refers to by its sources, lazy-loaded ones included.
+// Data URLs are not counted.
+func imageFiles(img *html.Node) map[string]bool {
+	files := map[string]bool{}
+	candidates := append(imageCandidates(img), ParseSrcset(GetAttribute(img, "data-srcset"))...)
+	for _, c := range candidates {
+		if strings.HasPrefix(c.URL, "data:") {
+			continue
+		}
+		if u, err := url.Parse(c.URL); err == nil {
+			if name := path.Base(u.Path); name != "." && name != "/" {
+				files[name] = true
+			}
+		}
+	}
+	return files
+}
+
+// Image types a <source> may declare to be taken.
+var sourceTypes = map[string]bool{
+	"image/jpeg": true, "image/png": true, "image/gif": true, "image/webp": true, "image/svg+xml": true,
+}
+
+// Replace a <picture> by a plain <img> using the variant chosen by the policy.
+// As a browser does, the variants are taken from the first <source> applying
+// regardless of the screen, else from the <img>.
+func replacePicture(picture *html.Node, policy ImagePolicy) {
+	var candidates []SrcsetCandidate
+	var img *html.Node
+	for c := picture.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		switch c.Data {
+		case "source":
+			if candidates == nil && sourceApplies(c) {
+				candidates = ParseSrcset(GetAttribute(c, "srcset"))
+			}
+		case "img":
+			img = c
+		}
+	}
+	if img == nil {
+		img = &html.Node{Type: html.ElementNode, Data: "img", DataAtom: atom.Img}
+	} else {
+		picture.RemoveChild(img)
+		if len(candidates) == 0 {
+			candidates = imageCandidates(img)
+		}
+	}
+	if best, ok := chooseCandidate(candidates, policy); ok {
+		setAttribute(img, "src", best.URL)
+	}
+	removeAttributes(img, "srcset", "sizes", "data-src", "loading")
+	ensureAlt(img, picture)
+	picture.Parent.InsertBefore(img, picture)
+	picture.Parent.RemoveChild(picture)
+}
+
+// Reports whether a <source> of a <picture> applies to any reader: it has no media
+// query other than "all" or "screen" and no type or a common image type.
+func sourceApplies(source *html.Node) bool {
+	media := strings.ToLower(strings.TrimSpace(GetAttribute(source, "media")))
+	if media != "" && media != "all" && media != "screen" {
+		return false
+	}
+	typ := strings.ToLower(strings.TrimSpace(GetAttribute(source, "type")))
+	return typ == "" || sourceTypes[typ]
+}
+
+// Reduce a single image to one source. Reports whether the image was changed.
+func reduceImage(img *html.Node, policy ImagePolicy) bool {
+	if GetAttribute(img, "srcset") == "" && GetAttribute(img, "data-src") == "" {
+		return false
+	}
+	if best, ok := chooseCandidate(imageCandidates(img), policy); ok {
+		setAttribute(img, "src", best.URL)
+	}
+	removeAttributes(img, "srcset", "sizes", "data-src", "loading")
+	ensureAlt(img, img)
+	return true
+}
+
+// All sources of an <img>: its srcset, a lazy-loaded data-src and its src.
+func imageCandidates(img *html.Node) []SrcsetCandidate {
+	candidates := ParseSrcset(GetAttribute(img, "srcset"))
+	for _, key := range []string{"data-src", "src"} {
+		if v := strings.TrimSpace(GetAttribute(img, key)); v != "" && !strings.HasPrefix(v, "data:") {
+			candidates = append(candidates, SrcsetCandidate{URL: v})
+		}
+	}
+	return candidates
+}
+
+// Choose the candidate matching the policy. Candidates without width descriptor
+// are only taken if there is no candidate with one.
+func chooseCandidate(candidates []SrcsetCandidate, policy ImagePolicy) (SrcsetCandidate, bool) {
+	var best SrcsetCandidate
+	bestWidth := -1
+	for _, c := range candidates {
+		w := descriptorWidth(c.Descriptor)
+		switch {
+		case bestWidth < 0:
+		case policy.Width <= 0:
+			if w <= bestWidth {
+				continue
+			}
+		case bestWidth >= policy.Width:
+			if w < policy.Width || w >= bestWidth {
+				continue
+			}
+		default:
+			if w <= bestWidth {
+				continue
+			}
+		}
+		best, bestWidth = c, w
+	}
+	return best, bestWidth >= 0
+}
+
+// Width given by a srcset descriptor. Density descriptors count as their factor,
+// missing descriptors as zero.
+func descriptorWidth(descriptor string) int {
+	if w, ok := strings.CutSuffix(descriptor, "w"); ok {
+		if n, err := strconv.Atoi(w); err == nil {
+			return n
+		}
+	}
+	if x, ok := strings.CutSuffix(descriptor, "x"); ok {
+		if f, err := strconv.ParseFloat(x, 64); err == nil {
+			return int(f)
+		}
+	}
+	return 0
+}
+
+// Use the caption of the surrounding <figure> as alternative text if the image has none.
+func ensureAlt(img, anchor *html.Node) {
+	if strings.TrimSpace(GetAttribute(img, "alt")) != "" {
+		return
+	}
+	for n := anchor.Parent; n != nil; n = n.Parent {
+		if n.Type == html.ElementNode && n.Data == "figure" {
+			if caption := FindElement(n, "figcaption"); caption != nil {
+				setAttribute(img, "alt", strings.Join(strings.Fields(TextContent(caption)), " "))
+				return
+			}
+			break
+		}
+	}
+	if !hasAttribute(img, "alt") {
+		setAttribute(img, "alt", "")
+	}
+}
+
+// Set the value of an attribute, adding it if necessary.
+func setAttribute(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+// Reports whether the node has the given attribute.
+func hasAttribute(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove the given attributes from the node.
+func removeAttributes(n *html.Node, keys ...string) {
+	kept := n.Attr[:0]
+	for _, a := range n.Attr {
+		drop := false
+		for _, k := range keys {
+			if a.Key == k {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, a)
+		}
+	}
+	n.Attr = kept
+}
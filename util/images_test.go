@@ -0,0 +1,79 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"testing"
+)
+
+func TestNormalizeImages(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		policy ImagePolicy
+		want   string
+	}{
+		{"picture",
+			`<figure><picture><source type="image/jxl" srcset="a-640.jxl 640w, a-1400.jxl 1400w">` +
+				`<source media="(max-width: 600px)" srcset="a-narrow.png 600w">` +
+				`<source srcset="a-640.png 640w, a-1000.png 1000w, a-1400.png 1400w"><img alt="Diagram" width="700" loading="lazy"></picture></figure>`,
+			ImagePolicy{Width: 800},
+			`<figure><img alt="Diagram" width="700" src="a-1000.png"/></figure>`},
+		{"picture source before image",
+			`<picture><source type="image/webp" srcset="a-1000.webp 1000w"><img srcset="a-1000.png 1000w, a-2000.png 2000w"></picture>`,
+			ImagePolicy{},
+			`<img src="a-1000.webp" alt=""/>`},
+		{"widest",
+			`<img src="a.png" srcset="a-1x.png 1x, a-2x.png 2x">`,
+			ImagePolicy{},
+			`<img src="a-2x.png" alt=""/>`},
+		{"caption as alt",
+			`<figure><img srcset="b-640.png 640w, b-1400.png 1400w" sizes="700px"><figcaption>A <em>nice</em> chart</figcaption></figure>`,
+			ImagePolicy{Width: 1000},
+			`<figure><img src="b-1400.png" alt="A nice chart"/><figcaption>A <em>nice</em> chart</figcaption></figure>`},
+		{"noscript fallback",
+			`<figure><div><img class="progressiveMedia-thumbnail" src="https://cdn.example.com/max/60/real.jpeg?q=20"><canvas></canvas>` +
+				`<img class="progressiveMedia-image" data-src="https://cdn.example.com/max/2000/real.jpeg"><img class="lazy" src="">` +
+				`<noscript><img class="progressiveMedia-noscript" src="https://cdn.example.com/max/800/real.jpeg"></noscript></div></figure>`,
+			ImagePolicy{Width: 800},
+			`<figure><div><img class="progressiveMedia-noscript" src="https://cdn.example.com/max/800/real.jpeg"/></div></figure>`},
+		{"noscript next to another image",
+			`<div><img class="thumbnail" src="other.png"><noscript><img src="real.jpeg"></noscript></div>`,
+			ImagePolicy{Width: 800},
+			`<div><img class="thumbnail" src="other.png"/><img src="real.jpeg"/></div>`},
+		{"plain image untouched",
+			`<img src="plain.png">`,
+			ImagePolicy{Width: 800},
+			`<img src="plain.png"/>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := mustParse(t, "<body>"+tt.doc+"</body>")
+			NormalizeImages(doc, tt.policy)
+			body := FindElement(doc, "body")
+			got := renderNode(t, body)
+			if want := "<body>" + tt.want + "</body>"; got != want {
+				t.Errorf("NormalizeImages() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"encoding/json"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Collects the JSON-LD objects embedded in the document.
+// Top level arrays and @graph containers are flattened; invalid blocks are ignored.
+func JSONLDObjects(doc *html.Node) []map[string]any {
+	var objects []map[string]any
+	var add func(v any)
+	add = func(v any) {
+		switch t := v.(type) {
+		case []any:
+			for _, e := range t {
+				add(e)
+			}
+		case map[string]any:
+			objects = append(objects, t)
+			if graph, ok := t["@graph"]; ok {
+				add(graph)
+			}
+		}
+	}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "script" &&
+			strings.EqualFold(GetAttribute(n, "type"), "application/ld+json") {
+			var v any
+			if err := json.Unmarshal([]byte(TextContentRaw(n)), &v); err == nil {
+				add(v)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return objects
+}
+
+// Reports whether the JSON-LD object describes an article.
+func IsJSONLDArticle(obj map[string]any) bool {
+	for _, t := range JSONLDStrings(obj["@type"]) {
+		switch t {
+		case "Article", "NewsArticle", "BlogPosting", "TechArticle", "SocialMediaPosting", "Report", "ScholarlyArticle":
+			return true
+		}
+	}
+	return false
+}
+
+// Converts a JSON-LD value which may be a string, an object with a name or
+// a list of those into a list of strings.
+func JSONLDStrings(v any) []string {
+	switch t := v.(type) {
+	case string:
+		if s := strings.TrimSpace(t); s != "" {
+			return []string{s}
+		}
+	case []any:
+		var result []string
+		for _, e := range t {
+			result = append(result, JSONLDStrings(e)...)
+		}
+		return result
+	case map[string]any:
+		for _, key := range []string{"name", "@value"} {
+			if s, ok := t[key].(string); ok && strings.TrimSpace(s) != "" {
+				return []string{strings.TrimSpace(s)}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// Shortest word considered as keyword.
+const minKeywordLength = 3
+
+// Built-in stopwords per language, extended by AddStopwords.
+var defaultStopwords = map[string]string{
+	"en": "a about above after again all also am an and any are as at be because been before being below between both but by can could did do does doing down during each even few for from further get got had has have having he her here hers him his how however i if in into is it its itself just like made make many may me might more most much must my new no nor not now of off often on once one only or other our out over own same she should so some such than that the their them then there these they this those through to too two under until up use used using very was way we well were what when where which while who whom why will with would you your",
+	"de": "aber alle allem allen aller alles als also am an ander andere auch auf aus bei bin bis bist da damit dann das dass dem den denn der des dich die dies diese dieser dieses dir doch dort du durch ein eine einem einen einer eines er es etwas euch für hat hatte haben hier ich ihm ihn ihr ihre im in ist ja jede jedem jeden jeder jedes jetzt kann kein keine man mehr mein mich mir mit muss nach nicht nichts noch nun nur ob oder ohne schon sehr sein seine sich sie sind so soll über um und uns unser unter viel vom von vor war waren was weil wenn wer werden wie wieder will wir wird wo zu zum zur",
+	"fr": "alors au aucun aussi autre aux avec avoir bon car ce cela ces cet cette ceux chaque comme comment dans de des donc dont du elle elles en encore est et être eux fait faire ici il ils je juste la le les leur leurs lui mais me même mes moi mon ne nos notre nous on ont ou où par pas peu peut plus pour pourquoi quand que quel quelle qui sa sans se ses si son sont sur ta te tes toi ton tous tout tres très tu un une vos votre vous",
+	"es": "al algo algunos ante antes como con contra cual cuando de del desde donde dos el ella ellas ellos en entre era es esa ese eso esta estaba estado este esto estos fue ha hace hasta hay la las le les lo los más me mi mis mucho muy nada ni no nos nosotros o otra otro para pero poco por porque que quien se sea ser si sin sobre son su sus también tanto te tiene todo todos tu un una uno unos y ya yo",
+	"it": "a abbiamo ad al alla alle anche ancora avere c che chi ci come con cosa da dal dalla dei del della delle di dopo e è ed era essere fa gli ha hanno ho i il in io la le lei li lo loro lui ma mi mio molto ne nei nel nella no noi non o per perché più po poi quale quando quanto quella quello questa questo se sei si sia siamo sono su sua sue suo sul sulla tra tu tutti tutto un una uno vi",
+	"pt": "a ao aos as até com como da das de dela dele do dos e é ela ele eles em entre era essa esse esta este eu foi há isso isto já lhe mais mas me mesmo meu minha muito na nas nem no nos nós o os ou para pela pelo por qual quando que quem se sem ser seu sua são também te tem ter um uma você",
+	"nl": "aan al als bij dan dat de der deze die dit doch doen door dus een en er ge geen had heb hebben heeft hem het hier hij hoe hun ik in is ja je kan kon maar me meer men met mij mijn na naar niet niets nog nu of om omdat ons ook op over reeds te tegen toch toen tot u uit uw van veel voor want waren was wat we wel werd wezen wie wij wil worden zal ze zelf zich zij zijn zo zonder zou",
+}
+
+// Extracts keywords from a batch of documents by TF-IDF: words frequent in a
+// document but rare in the other documents of the batch score best.
+type KeywordExtractor struct {
+	stopwords map[string]map[string]bool
+	docs      []keywordDoc
+	// Number of documents containing each term.
+	df map[string]int
+}
+
+// Terms and tags of a document added to the extractor.
+type keywordDoc struct {
+	tf   map[string]int
+	tags []string
+}
+
+// Create an extractor using the built-in stopwords.
+func NewKeywordExtractor() *KeywordExtractor {
+	k := &KeywordExtractor{stopwords: map[string]map[string]bool{}, df: map[string]int{}}
+	for lang, words := range defaultStopwords {
+		k.AddStopwords(lang, strings.Fields(words))
+	}
+	return k
+}
+
+// Add stopwords for the given language.
+func (k *KeywordExtractor) AddStopwords(lang string, words []string) {
+	if k.stopwords[lang] == nil {
+		k.stopwords[lang] = map[string]bool{}
+	}
+	for _, w := range words {
+		k.stopwords[lang][strings.ToLower(w)] = true
+	}
+}
+
+// Read a stopword list: one word per line, lines starting with # are ignored.
+func ReadStopwords(r io.Reader) ([]string, error) {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			words = append(words, line)
+		}
+	}
+	return words, scanner.Err()
+}
+
+// Add the text of a document in the given language together with tags known from
+// its metadata. Documents of unknown language are filtered by the English stopwords.
+// Returns the index of the document used to query its keywords.
+func (k *KeywordExtractor) Add(text, lang string, tags []string) int {
+	stopwords := k.stopwords[lang]
+	if lang == "" {
+		stopwords = k.stopwords["en"]
+	}
+	tf := map[string]int{}
+	for _, w := range keywordTerms(text) {
+		if !stopwords[w] {
+			tf[w]++
+		}
+	}
+	for term := range tf {
+		k.df[term]++
+	}
+	k.docs = append(k.docs, keywordDoc{tf: tf, tags: tags})
+	return len(k.docs) - 1
+}
+
+// Split a text into lower case words of letters, digits and inner hyphens; words
+// shorter than minKeywordLength and numbers are dropped.
+func keywordTerms(text string) []string {
+	var terms []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	}) {
+		w = strings.Trim(w, "-")
+		if len([]rune(w)) < minKeywordLength || strings.IndexFunc(w, unicode.IsLetter) < 0 {
+			continue
+		}
+		terms = append(terms, w)
+	}
+	return terms
+}
+
+// Return at most limit keywords of the document: its tags first, then the terms with
+// the best TF-IDF scores among the documents added so far.
+func (k *KeywordExtractor) Keywords(doc, limit int) []string {
+	d := k.docs[doc]
+	var keywords []string
+	seen := map[string]bool{}
+	for _, tag := range d.tags {
+		if len(keywords) < limit && !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			keywords = append(keywords, tag)
+		}
+	}
+	type scored struct {
+		term  string
+		score float64
+	}
+	var terms []scored
+	n := float64(len(k.docs))
+	for term, tf := range d.tf {
+		if tf < 2 || seen[term] {
+			continue
+		}
+		idf := math.Log((n+1)/float64(k.df[term]+1)) + 1
+		terms = append(terms, scored{term, float64(tf) * idf})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].score != terms[j].score {
+			return terms[i].score > terms[j].score
+		}
+		return terms[i].term < terms[j].term
+	})
+	for _, t := range terms {
+		if len(keywords) == limit {
+			break
+		}
+		keywords = append(keywords, t.term)
+	}
+	return keywords
+}
+
+// Record the keywords in the document as <meta name="keywords">, replacing an
+// existing one.
+func SetKeywords(doc *html.Node, keywords []string) {
+	head := FindElement(doc, "head")
+	if head == nil || len(keywords) == 0 {
+		return
+	}
+	for c := head.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && c.Data == "meta" && strings.EqualFold(GetAttribute(c, "name"), "keywords") {
+			head.RemoveChild(c)
+		}
+		c = next
+	}
+	head.AppendChild(newNamedMeta("keywords", strings.Join(keywords, ", ")))
+}
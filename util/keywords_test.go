@@ -0,0 +1,75 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeywordExtractor(t *testing.T) {
+	k := NewKeywordExtractor()
+	goDoc := k.Add("Go tests are table driven. Table driven tests keep the tests of Go code compact; "+
+		"the tests run fast and the code stays readable.", "en", []string{"Go", "Testing"})
+	breadDoc := k.Add("Sourdough bread needs a starter. The starter and the dough need time, "+
+		"and the bread needs a hot oven. The code of baking: patience.", "en", nil)
+	deDoc := k.Add("Der Sauerteig braucht Zeit. Der Sauerteig und das Brot brauchen einen heißen Ofen, "+
+		"das Brot braucht Geduld.", "de", nil)
+
+	tests := []struct {
+		doc   int
+		limit int
+		want  []string
+	}{
+		{goDoc, 5, []string{"Go", "Testing", "tests", "driven", "table"}},
+		{goDoc, 1, []string{"Go"}},
+		{breadDoc, 3, []string{"bread", "needs", "starter"}},
+		{deDoc, 3, []string{"braucht", "brot", "sauerteig"}},
+	}
+	for _, tt := range tests {
+		if got := k.Keywords(tt.doc, tt.limit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Keywords(%d, %d) = %v, want %v", tt.doc, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestReadStopwords(t *testing.T) {
+	words, err := ReadStopwords(strings.NewReader("# custom stopwords\nfoo\n\n  bar \n"))
+	if err != nil || !reflect.DeepEqual(words, []string{"foo", "bar"}) {
+		t.Fatalf("ReadStopwords() = %v, %v", words, err)
+	}
+	k := NewKeywordExtractor()
+	k.AddStopwords("en", words)
+	i := k.Add("foo foo foo bar bar baz baz", "en", nil)
+	if got := k.Keywords(i, 3); !reflect.DeepEqual(got, []string{"baz"}) {
+		t.Errorf("Keywords() = %v, want [baz]", got)
+	}
+}
+
+func TestSetKeywords(t *testing.T) {
+	doc := mustParse(t, `<html><head><meta name="keywords" content="Tag:old"></head><body></body></html>`)
+	SetKeywords(doc, []string{"Go", "testing"})
+	if got := MetaContents(doc, "keywords"); !reflect.DeepEqual(got, []string{"Go, testing"}) {
+		t.Errorf("SetKeywords() wrote %v", got)
+	}
+}
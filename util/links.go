@@ -0,0 +1,221 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Query parameters removed from links unless configured otherwise.
+// A trailing * matches any parameter starting with the given prefix.
+var DefaultTrackingParams = []string{
+	"utm_*",
+	"fbclid",
+	"gclid",
+	"mc_cid",
+	"mc_eid",
+	"ref_src",
+	"_hsenc",
+	"_hsmi",
+}
+
+// Query parameters removed from links to a host or its subdomains in addition to
+// the configured ones, as they only track on that site.
+var hostTrackingParams = []struct {
+	host   string
+	params []string
+}{
+	{"medium.com", []string{"source"}},
+}
+
+// Redirect services and the query parameter holding the real target.
+var redirectors = []struct {
+	host  string
+	path  string
+	param string
+}{
+	{"medium.com", "/r/", "url"},
+	{"www.google.com", "/url", "q"},
+	{"l.facebook.com", "/l.php", "u"},
+	{"out.reddit.com", "", "url"},
+	{"www.linkedin.com", "/redir/redirect", "url"},
+}
+
+// Number of links changed by RewriteLinks, by kind of change.
+type LinkCounts struct {
+	Unwrapped  int
+	Stripped   int
+	Normalized int
+}
+
+// Unwrap redirect links, strip tracking parameters and normalize the URLs
+// of all <a href> and <img src> attributes in the document.
+func RewriteLinks(doc *html.Node, trackingParams []string) LinkCounts {
+	var counts LinkCounts
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			key := ""
+			switch n.Data {
+			case "a":
+				key = "href"
+			case "img":
+				key = "src"
+			}
+			for i, a := range n.Attr {
+				if a.Key == key && a.Val != "" {
+					n.Attr[i].Val = cleanURL(a.Val, trackingParams, &counts)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return counts
+}
+
+// Clean a single URL and count what was changed.
+// URLs which cannot be parsed or use schemes other than http(s) are returned unchanged.
+func cleanURL(raw string, trackingParams []string, counts *LinkCounts) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "" && !strings.EqualFold(u.Scheme, "http") && !strings.EqualFold(u.Scheme, "https")) {
+		return raw
+	}
+	changed := false
+	if target, ok := unwrapRedirect(u); ok {
+		u = target
+		counts.Unwrapped++
+		changed = true
+	}
+	if stripTrackingParams(u, append(trackingParams[:len(trackingParams):len(trackingParams)], siteTrackingParams(u)...)) {
+		counts.Stripped++
+		changed = true
+	}
+	before := u.String()
+	normalizeURL(u)
+	after := u.String()
+	if after != before {
+		counts.Normalized++
+		changed = true
+	}
+	if !changed {
+		return raw
+	}
+	return after
+}
+
+// Determine the target of a redirect link.
+func unwrapRedirect(u *url.URL) (*url.URL, bool) {
+	host := strings.ToLower(u.Hostname())
+	for _, r := range redirectors {
+		if host != r.host || !strings.HasPrefix(u.Path, r.path) {
+			continue
+		}
+		target := u.Query().Get(r.param)
+		if t, err := url.Parse(target); err == nil && t.IsAbs() {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Remove the tracking parameters from the query. The remaining parameters keep their
+// order and encoding. Reports whether anything was removed.
+func stripTrackingParams(u *url.URL, trackingParams []string) bool {
+	if u.RawQuery == "" {
+		return false
+	}
+	var kept []string
+	stripped := false
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		key, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if isTrackingParam(key, trackingParams) {
+			stripped = true
+			continue
+		}
+		kept = append(kept, param)
+	}
+	if stripped {
+		u.RawQuery = strings.Join(kept, "&")
+	}
+	return stripped
+}
+
+// The tracking parameters specific to the host of the URL.
+func siteTrackingParams(u *url.URL) []string {
+	host := strings.ToLower(u.Hostname())
+	var params []string
+	for _, h := range hostTrackingParams {
+		if host == h.host || strings.HasSuffix(host, "."+h.host) {
+			params = append(params, h.params...)
+		}
+	}
+	return params
+}
+
+// Reports whether the parameter matches one of the given names or prefixes.
+func isTrackingParam(key string, trackingParams []string) bool {
+	for _, p := range trackingParams {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Lower case scheme and host, drop default ports and empty queries and fragments.
+func normalizeURL(u *url.URL) {
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Host != "" {
+		host := strings.ToLower(u.Hostname())
+		port := u.Port()
+		if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+			port = ""
+		}
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		u.Host = host
+		if port != "" {
+			u.Host = host + ":" + port
+		}
+		if u.Path == "" {
+			u.Path = "/"
+		}
+	}
+	u.ForceQuery = false
+	if u.Fragment == "" {
+		u.RawFragment = ""
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"testing"
+)
+
+func TestCleanURL(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+		kind string
+	}{
+		{"medium redirect", "https://medium.com/r/?url=https%3A%2F%2Fgo.dev%2Fblog%3Futm_source%3Dmedium", "https://go.dev/blog", "unwrapped"},
+		{"google redirect", "https://www.google.com/url?q=https://example.com/a&sa=D", "https://example.com/a", "unwrapped"},
+		{"medium source", "https://medium.com/@jane/post-123?source=post_page-----abc", "https://medium.com/@jane/post-123", "stripped"},
+		{"medium subdomain source", "https://jane.medium.com/post-123?source=rss&id=7", "https://jane.medium.com/post-123?id=7", "stripped"},
+		{"relative", "/@jane?utm_source=author_recirc", "/@jane", "stripped"},
+		{"keeps other params", "https://example.com/p?id=7&utm_campaign=x", "https://example.com/p?id=7", "stripped"},
+		{"keeps order and encoding", "https://example.com/p?b=2&utm_source=x&a=%7Efoo+bar&c", "https://example.com/p?b=2&a=%7Efoo+bar&c", "stripped"},
+		{"source kept on other sites", "https://example.com/search?source=docs", "https://example.com/search?source=docs", ""},
+		{"source kept on lookalike host", "https://notmedium.com/p?source=docs", "https://notmedium.com/p?source=docs", ""},
+		{"normalize host and port", "HTTPS://Example.COM:443", "https://example.com/", "normalized"},
+		{"untouched", "https://example.com/p?b=2&a=1#frag", "https://example.com/p?b=2&a=1#frag", ""},
+		{"mailto", "mailto:someone@example.com?subject=utm_x", "mailto:someone@example.com?subject=utm_x", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var counts LinkCounts
+			if got := cleanURL(tt.raw, DefaultTrackingParams, &counts); got != tt.want {
+				t.Errorf("cleanURL() = %v, want %v", got, tt.want)
+			}
+			got := map[string]int{"unwrapped": counts.Unwrapped, "stripped": counts.Stripped, "normalized": counts.Normalized}
+			if tt.kind != "" && got[tt.kind] != 1 {
+				t.Errorf("cleanURL() counts = %+v, want one %s", counts, tt.kind)
+			}
+			if tt.kind == "" && counts != (LinkCounts{}) {
+				t.Errorf("cleanURL() counts = %+v, want none", counts)
+			}
+		})
+	}
+}
+
+func TestRewriteLinks(t *testing.T) {
+	doc := mustParse(t, `<body><a href="https://medium.com/r/?url=https%3A%2F%2Fgo.dev">Go</a>`+
+		`<img src="https://miro.medium.com/img.png?utm_source=x"><a href="#top">Top</a></body>`)
+	counts := RewriteLinks(doc, DefaultTrackingParams)
+	if counts.Unwrapped != 1 || counts.Stripped != 1 {
+		t.Errorf("RewriteLinks() = %+v, want one unwrapped and one stripped link", counts)
+	}
+	if got := GetAttribute(FindElement(doc, "a"), "href"); got != "https://go.dev/" {
+		t.Errorf("RewriteLinks() href = %v", got)
+	}
+	if got := GetAttribute(FindElement(doc, "img"), "src"); got != "https://miro.medium.com/img.png" {
+		t.Errorf("RewriteLinks() src = %v", got)
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// A shrunk document which becomes a part of a merged series.
+type SeriesPart struct {
+	Title string
+	// Canonical URL or file name of the original clipping.
+	Source    string
+	Published time.Time
+	Doc       *html.Node
+}
+
+// Meta tags and links describing a single part; they are not taken over into the
+// head of a merged document.
+var partOnlyHeadKeys = map[string]bool{
+	"description": true, "og:title": true, "og:description": true, "og:url": true,
+	"article:published_time": true, "article:modified_time": true, "canonical": true,
+}
+
+// Sort the parts by publish date, keeping the given order for equal dates.
+// Parts without a date go last.
+func SortPartsByDate(parts []SeriesPart) {
+	sort.SliceStable(parts, func(i, j int) bool {
+		pi, pj := parts[i].Published, parts[j].Published
+		if pi.IsZero() || pj.IsZero() {
+			return !pi.IsZero() && pj.IsZero()
+		}
+		return pi.Before(pj)
+	})
+}
+
+// Derive the title of a series from the titles of its parts: their common leading
+// words without a trailing "Part" and punctuation. Falls back to the first title.
+func SeriesTitle(titles []string) string {
+	if len(titles) == 0 {
+		return ""
+	}
+	common := strings.Fields(titles[0])
+	for _, t := range titles[1:] {
+		words := strings.Fields(t)
+		n := 0
+		for n < len(common) && n < len(words) && common[n] == words[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	for len(common) > 0 && strings.EqualFold(strings.Trim(common[len(common)-1], ",:;-–—|#()"), "part") {
+		common = common[:len(common)-1]
+	}
+	title := strings.TrimRight(strings.Join(common, " "), " ,:;-–—|(#")
+	if len(titles) == 1 || len(title) < 3 {
+		return titles[0]
+	}
+	return title
+}
+
+// Merge the parts into a single document. The heads of the parts are combined
+// without duplicates, the body starts with the title and a table of contents followed
+// by a <section> for each part, introduced by a header naming the part.
+func MergeParts(title string, parts []SeriesPart) *html.Node {
+	doc := &html.Node{Type: html.DocumentNode}
+	root := newElement("html")
+	head := newElement("head")
+	body := newElement("body")
+	doc.AppendChild(root)
+	root.AppendChild(head)
+	root.AppendChild(body)
+
+	head.AppendChild(newMeta("charset", "utf-8"))
+	head.AppendChild(withText(newElement("title"), title))
+	mergeHeads(head, parts)
+
+	header := newElement("header", "class", "shrinkr-series")
+	header.AppendChild(withText(newElement("h1"), title))
+	toc := newElement("ol")
+	for i, p := range parts {
+		li := newElement("li")
+		li.AppendChild(withText(newElement("a", "href", fmt.Sprintf("#part-%d", i+1)), p.Title))
+		toc.AppendChild(li)
+	}
+	nav := newElement("nav", "class", "shrinkr-toc")
+	nav.AppendChild(toc)
+	header.AppendChild(nav)
+	body.AppendChild(header)
+
+	for i, p := range parts {
+		section := newElement("section", "id", fmt.Sprintf("part-%d", i+1), "class", "shrinkr-part")
+		section.AppendChild(partHeader(p, i+1, len(parts)))
+		if b := FindElement(p.Doc, "body"); b != nil {
+			prefixIDs(b, fmt.Sprintf("part-%d-", i+1))
+			for c := b.FirstChild; c != nil; c = b.FirstChild {
+				b.RemoveChild(c)
+				section.AppendChild(c)
+			}
+		}
+		body.AppendChild(section)
+	}
+	return doc
+}
+
+// Prefix the ids below n and the links to them, so that the ids of the parts do not
+// collide in the merged document.
+func prefixIDs(n *html.Node, prefix string) {
+	if n.Type == html.ElementNode {
+		for i, a := range n.Attr {
+			switch {
+			case a.Key == "id" && a.Val != "":
+				n.Attr[i].Val = prefix + a.Val
+			case a.Key == "href" && len(a.Val) > 1 && a.Val[0] == '#':
+				n.Attr[i].Val = "#" + prefix + a.Val[1:]
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		prefixIDs(c, prefix)
+	}
+}
+
+// Move the head elements of the parts over, skipping duplicates, provenance and
+// everything describing a single part.
+func mergeHeads(head *html.Node, parts []SeriesPart) {
+	seen := map[string]bool{}
+	for _, p := range parts {
+		h := FindElement(p.Doc, "head")
+		if h == nil {
+			continue
+		}
+		for c := h.FirstChild; c != nil; {
+			next := c.NextSibling
+			if key, ok := mergedHeadKey(c); ok && !seen[key] {
+				seen[key] = true
+				h.RemoveChild(c)
+				head.AppendChild(c)
+			}
+			c = next
+		}
+	}
+}
+
+// Key identifying duplicates of a head element: the name of meta tags, else the
+// rendered element. Reports false if the element is not taken over.
+func mergedHeadKey(n *html.Node) (string, bool) {
+	if n.Type != html.ElementNode || n.Data == "title" || isProvenanceNode(n) || hasAttribute(n, "charset") {
+		return "", false
+	}
+	key := GetAttribute(n, "name") + GetAttribute(n, "property")
+	if n.Data == "link" {
+		key = GetAttribute(n, "rel")
+	}
+	if partOnlyHeadKeys[key] {
+		return "", false
+	}
+	if n.Data != "meta" || key == "" {
+		var buf bytes.Buffer
+		_ = html.Render(&buf, n)
+		key = buf.String()
+	}
+	return key, true
+}
+
+// The header introducing a part: its number, title, link to the original and date.
+func partHeader(p SeriesPart, n, total int) *html.Node {
+	header := newElement("p", "class", "shrinkr-part-header")
+	header.AppendChild(&html.Node{Type: html.TextNode, Data: fmt.Sprintf("Part %d of %d: ", n, total)})
+	if strings.HasPrefix(p.Source, "http://") || strings.HasPrefix(p.Source, "https://") {
+		header.AppendChild(withText(newElement("a", "href", p.Source), p.Title))
+	} else {
+		header.AppendChild(&html.Node{Type: html.TextNode, Data: p.Title})
+	}
+	if !p.Published.IsZero() {
+		header.AppendChild(&html.Node{Type: html.TextNode, Data: ", " + p.Published.Format("January 2, 2006")})
+	}
+	return header
+}
+
+// Create an element with the given attributes as key value pairs.
+func newElement(tag string, attrs ...string) *html.Node {
+	n := &html.Node{Type: html.ElementNode, Data: tag, DataAtom: atom.Lookup([]byte(tag))}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		n.Attr = append(n.Attr, html.Attribute{Key: attrs[i], Val: attrs[i+1]})
+	}
+	return n
+}
+
+// Append a text node to the element and return it.
+func withText(n *html.Node, text string) *html.Node {
+	n.AppendChild(&html.Node{Type: html.TextNode, Data: text})
+	return n
+}
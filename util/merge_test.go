@@ -0,0 +1,87 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeriesTitle(t *testing.T) {
+	tests := []struct {
+		titles []string
+		want   string
+	}{
+		{[]string{"Go Concurrency, Part 1", "Go Concurrency, Part 2"}, "Go Concurrency"},
+		{[]string{"Testing in Go (Part 1): Basics", "Testing in Go (Part 2): Mocks"}, "Testing in Go"},
+		{[]string{"Alpha", "Beta"}, "Alpha"},
+		{[]string{"Single"}, "Single"},
+	}
+	for _, tt := range tests {
+		if got := SeriesTitle(tt.titles); got != tt.want {
+			t.Errorf("SeriesTitle(%q) = %q, want %q", tt.titles, got, tt.want)
+		}
+	}
+}
+
+func TestSortPartsByDate(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	parts := []SeriesPart{{Title: "undated"}, {Title: "3", Published: day(3)}, {Title: "1", Published: day(1)}}
+	SortPartsByDate(parts)
+	var got []string
+	for _, p := range parts {
+		got = append(got, p.Title)
+	}
+	if strings.Join(got, ",") != "1,3,undated" {
+		t.Errorf("SortPartsByDate() = %v", got)
+	}
+}
+
+func TestMergeParts(t *testing.T) {
+	part := func(n string) *SeriesPart {
+		doc := mustParse(t, `<html><head><meta charset="utf-8"><title>Part `+n+`</title>`+
+			`<meta name="author" content="Jane"><meta name="og:title" content="Part `+n+`">`+
+			`<link rel="canonical" href="https://example.com/`+n+`"><style>p{margin:0}</style>`+
+			`<meta name="generator" content="shrinkr 1.0.0"></head>`+
+			`<body><article><h1 id="intro">Part `+n+`</h1><p>Text `+n+` <a href="#intro">top</a></p></article></body></html>`)
+		return &SeriesPart{Title: "Part " + n, Source: "https://example.com/" + n, Doc: doc}
+	}
+	p1, p2 := part("1"), part("2")
+	p2.Published = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	p2.Source = "part2.html"
+	doc := MergeParts("Series", []SeriesPart{*p1, *p2})
+
+	wantHead := `<head><meta charset="utf-8"/><title>Series</title><meta name="author" content="Jane"/><style>p{margin:0}</style></head>`
+	if got := renderNode(t, FindElement(doc, "head")); got != wantHead {
+		t.Errorf("merged head =\n%s\nwant\n%s", got, wantHead)
+	}
+	wantBody := `<body><header class="shrinkr-series"><h1>Series</h1><nav class="shrinkr-toc"><ol>` +
+		`<li><a href="#part-1">Part 1</a></li><li><a href="#part-2">Part 2</a></li></ol></nav></header>` +
+		`<section id="part-1" class="shrinkr-part"><p class="shrinkr-part-header">Part 1 of 2: <a href="https://example.com/1">Part 1</a></p>` +
+		`<article><h1 id="part-1-intro">Part 1</h1><p>Text 1 <a href="#part-1-intro">top</a></p></article></section>` +
+		`<section id="part-2" class="shrinkr-part"><p class="shrinkr-part-header">Part 2 of 2: Part 2, February 1, 2024</p>` +
+		`<article><h1 id="part-2-intro">Part 2</h1><p>Text 2 <a href="#part-2-intro">top</a></p></article></section></body>`
+	if got := renderNode(t, FindElement(doc, "body")); got != wantBody {
+		t.Errorf("merged body =\n%s\nwant\n%s", got, wantBody)
+	}
+}
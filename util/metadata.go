@@ -0,0 +1,248 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Descriptive data of an article as found in the clipped document.
+type Metadata struct {
+	Title        string     `json:"title,omitempty"`
+	Authors      []string   `json:"authors,omitempty"`
+	Published    *time.Time `json:"published,omitempty"`
+	Modified     *time.Time `json:"modified,omitempty"`
+	SiteName     string     `json:"siteName,omitempty"`
+	Tags         []string   `json:"tags,omitempty"`
+	CanonicalURL string     `json:"canonicalUrl,omitempty"`
+	Language     string     `json:"language,omitempty"`
+	Description  string     `json:"description,omitempty"`
+	LeadImage    string     `json:"leadImage,omitempty"`
+}
+
+// Layouts tried when parsing dates found in meta data.
+var metadataTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Extract the meta data of the article from OpenGraph and article:* meta tags,
+// JSON-LD blocks and Medium specific markup. Earlier sources take precedence.
+func ExtractMetadata(doc *html.Node) Metadata {
+	var md Metadata
+	var article map[string]any
+	for _, obj := range JSONLDObjects(doc) {
+		if IsJSONLDArticle(obj) {
+			article = obj
+			break
+		}
+	}
+	ld := func(key string) []string {
+		if article == nil {
+			return nil
+		}
+		return JSONLDStrings(article[key])
+	}
+
+	if t, ok := ResolveTitle(doc); ok {
+		md.Title = t.Title
+	}
+	md.Authors = uniqueStrings(append(append(append(
+		nonURLs(ld("author")),
+		MetaContents(doc, "author")...),
+		mediumAuthors(doc)...),
+		nonURLs(MetaContents(doc, "article:author"))...))
+	md.Published = firstTime(MetaContent(doc, "article:published_time"), first(ld("datePublished")), timeInArticle(doc))
+	md.Modified = firstTime(MetaContent(doc, "article:modified_time"), first(ld("dateModified")))
+	md.SiteName = firstString(MetaContent(doc, "og:site_name"), publisherName(article))
+	md.Tags = uniqueStrings(append(MetaContents(doc, "article:tag"), keywords(doc, article)...))
+	md.CanonicalURL = firstString(LinkHref(doc, "canonical"), MetaContent(doc, "og:url"), first(ld("url")), first(ld("mainEntityOfPage")))
+	md.Language = firstString(documentLanguage(doc), first(ld("inLanguage")), MetaContent(doc, "og:locale"))
+	md.Description = firstString(MetaContent(doc, "description"), MetaContent(doc, "og:description"), first(ld("description")))
+	md.LeadImage = firstString(MetaContent(doc, "og:image"), MetaContent(doc, "twitter:image"), first(ld("image")))
+	return md
+}
+
+// Returns the contents of all <meta> elements whose name or property matches the given key.
+func MetaContents(doc *html.Node, key string) []string {
+	var values []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			if strings.EqualFold(GetAttribute(n, "name"), key) || strings.EqualFold(GetAttribute(n, "property"), key) {
+				if v := strings.TrimSpace(GetAttribute(n, "content")); v != "" {
+					values = append(values, v)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return values
+}
+
+// Authors marked up the way Medium does it.
+func mediumAuthors(doc *html.Node) []string {
+	var authors []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" &&
+			(GetAttribute(n, "data-testid") == "authorName" || GetAttribute(n, "rel") == "author") {
+			if name := strings.TrimSpace(TextContent(n)); name != "" {
+				authors = append(authors, name)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return authors
+}
+
+// Tags from the keywords meta tag and the JSON-LD keywords, separated by commas.
+// Medium prefixes its keywords with their kind; only tags and topics are kept of those.
+func keywords(doc *html.Node, article map[string]any) []string {
+	medium := DetectSite(doc) == "medium"
+	var raw []string
+	for _, v := range MetaContents(doc, "keywords") {
+		raw = append(raw, strings.Split(v, ",")...)
+	}
+	if article != nil {
+		for _, v := range JSONLDStrings(article["keywords"]) {
+			raw = append(raw, strings.Split(v, ",")...)
+		}
+	}
+	var tags []string
+	for _, k := range raw {
+		k = strings.TrimSpace(k)
+		if kind, value, ok := strings.Cut(k, ":"); ok && medium {
+			if kind != "Tag" && kind != "Topic" {
+				continue
+			}
+			k = strings.TrimSpace(value)
+		}
+		if k != "" {
+			tags = append(tags, k)
+		}
+	}
+	return tags
+}
+
+// Name of the publisher given in the JSON-LD article.
+func publisherName(article map[string]any) string {
+	if article == nil {
+		return ""
+	}
+	if p, ok := article["publisher"].(map[string]any); ok {
+		if name, ok := p["name"].(string); ok {
+			return strings.TrimSpace(name)
+		}
+	}
+	return ""
+}
+
+// The datetime of the first <time> element inside the article.
+func timeInArticle(doc *html.Node) string {
+	root := FindElement(doc, "article")
+	if root == nil {
+		return ""
+	}
+	if t := FindElement(root, "time"); t != nil {
+		return GetAttribute(t, "datetime")
+	}
+	return ""
+}
+
+// The language given by the lang attribute of the <html> element.
+func documentLanguage(doc *html.Node) string {
+	if h := FindElement(doc, "html"); h != nil {
+		return strings.TrimSpace(GetAttribute(h, "lang"))
+	}
+	return ""
+}
+
+// Parse the first of the given values which is a valid time.
+// Returns nil if there is none.
+func firstTime(values ...string) *time.Time {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		for _, layout := range metadataTimeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return &t
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the first non-empty value.
+func firstString(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Returns the first element of the list or an empty string.
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Drop values which are URLs, e.g. article:author pointing to a profile page.
+func nonURLs(values []string) []string {
+	var result []string
+	for _, v := range values {
+		if !strings.HasPrefix(v, "http://") && !strings.HasPrefix(v, "https://") {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// Remove duplicates ignoring case while keeping the order.
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, v := range values {
+		key := strings.ToLower(v)
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const metadataDoc = `<html lang="en"><head>
+<title>Clean Code in Go | by Jane Doe | Medium</title>
+<meta property="al:android:app_name" content="Medium">
+<meta property="og:site_name" content="Medium">
+<meta name="description" content="How to write clean Go.">
+<meta property="og:image" content="https://miro.medium.com/lead.png">
+<meta property="article:published_time" content="2024-03-01T10:15:00.123Z">
+<meta property="article:author" content="https://medium.com/@jane">
+<meta name="author" content="Jane Doe">
+<link rel="canonical" href="https://medium.com/@jane/clean-code-in-go-123">
+<script type="application/ld+json">{"@type":"NewsArticle","headline":"Clean Code in Go",
+"author":{"@type":"Person","name":"Jane Doe"},"dateModified":"2024-03-02T08:00:00Z",
+"keywords":["Tag:Golang","Tag:Clean Code","Elevated:false","Topic:Programming"]}</script>
+</head><body><article><a data-testid="authorName" href="/@jane">Jane Doe</a><p>Text</p></article></body></html>`
+
+func TestExtractMetadata(t *testing.T) {
+	md := ExtractMetadata(mustParse(t, metadataDoc))
+	published := time.Date(2024, 3, 1, 10, 15, 0, 123000000, time.UTC)
+	modified := time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC)
+	want := Metadata{
+		Title:        "Clean Code in Go",
+		Authors:      []string{"Jane Doe"},
+		Published:    &published,
+		Modified:     &modified,
+		SiteName:     "Medium",
+		Tags:         []string{"Golang", "Clean Code", "Programming"},
+		CanonicalURL: "https://medium.com/@jane/clean-code-in-go-123",
+		Language:     "en",
+		Description:  "How to write clean Go.",
+		LeadImage:    "https://miro.medium.com/lead.png",
+	}
+	if !reflect.DeepEqual(md, want) {
+		t.Errorf("ExtractMetadata() = %+v, want %+v", md, want)
+	}
+}
+
+func TestExtractMetadata_Empty(t *testing.T) {
+	md := ExtractMetadata(mustParse(t, `<html><head></head><body></body></html>`))
+	if !reflect.DeepEqual(md, Metadata{}) {
+		t.Errorf("ExtractMetadata() = %+v, want empty metadata", md)
+	}
+	if data, err := json.Marshal(md); err != nil || string(data) != "{}" {
+		t.Errorf("json.Marshal() = %s, %v, want {}", data, err)
+	}
+}
+
+func TestExtractMetadata_namesAndKeywords(t *testing.T) {
+	md := ExtractMetadata(mustParse(t, `<html><head><meta name="keywords" content="C++: the good parts, Go">`+
+		`<script type="application/ld+json">{"@type":"BlogPosting","headline":"H",`+
+		`"author":[{"@type":"Person","url":"https://example.com/kim"},{"@type":"Person","@id":"#sam"},"https://example.com/jo",{"name":"Kim Ops"}]}</script>`+
+		`</head><body></body></html>`))
+	if want := []string{"Kim Ops"}; !reflect.DeepEqual(md.Authors, want) {
+		t.Errorf("ExtractMetadata() authors = %q, want %q", md.Authors, want)
+	}
+	if want := []string{"C++: the good parts", "Go"}; !reflect.DeepEqual(md.Tags, want) {
+		t.Errorf("ExtractMetadata() tags = %q, want %q", md.Tags, want)
+	}
+}
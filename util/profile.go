@@ -0,0 +1,308 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// A profile bundles the knowledge about the pages of a site: how to recognize them,
+// where their content is and which boilerplate inside the content can go.
+type Profile struct {
+	Name        string
+	Description string
+	// Reports whether the document was clipped from the site.
+	Detect func(doc *html.Node) bool
+	// Names of the extraction strategies locating the content, tried before the configured ones.
+	Strategies []string
+	// Boilerplate removed from the content.
+	Removals []RemovalRule
+	// Patterns matching the suffix the site appends to its titles.
+	TitleSuffixes []string
+	// Matches the element naming the site, e.g. the site title in the page header.
+	// The name is stripped from the end of titles like og:site_name.
+	SiteName func(n *html.Node) bool
+}
+
+// A rule selecting elements to be removed.
+type RemovalRule struct {
+	Description string
+	Match       func(n *html.Node) bool
+}
+
+var profiles = map[string]Profile{}
+
+// Make a profile available by its name.
+// Panics if a profile with the same name is registered already or a title pattern is invalid.
+func RegisterProfile(p Profile) {
+	if _, ok := profiles[p.Name]; ok {
+		panic("profile registered twice: " + p.Name)
+	}
+	for _, expr := range p.TitleSuffixes {
+		if err := AddTitleSuffixPattern(p.Name, expr); err != nil {
+			panic(err)
+		}
+	}
+	profiles[p.Name] = p
+}
+
+// Look up a registered profile by its name.
+func LookupProfile(name string) (Profile, error) {
+	p, ok := profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %q, available: %s", name, strings.Join(ProfileNames(), ", "))
+	}
+	return p, nil
+}
+
+// Returns the names of all registered profiles in alphabetical order.
+func ProfileNames() []string {
+	var names []string
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Determine the profile matching the document.
+// The boolean result is false if no registered profile recognizes the document.
+func DetectProfile(doc *html.Node) (Profile, bool) {
+	for _, name := range ProfileNames() {
+		if p := profiles[name]; p.Detect != nil && p.Detect(doc) {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// Remove all elements below root matching one of the rules.
+// Returns the number of elements removed.
+func ApplyRemovals(root *html.Node, rules []RemovalRule) int {
+	removed := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			if c.Type == html.ElementNode && matchesAny(c, rules) {
+				n.RemoveChild(c)
+				removed++
+			} else {
+				walk(c)
+			}
+			c = next
+		}
+	}
+	walk(root)
+	return removed
+}
+
+// Reports whether one of the rules matches the node.
+func matchesAny(n *html.Node, rules []RemovalRule) bool {
+	for _, r := range rules {
+		if r.Match(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// Search the subtree below the given node for the first element matching the predicate.
+// Returns nil if there is no such element.
+func FirstElement(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if n.Type == html.ElementNode && match(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := FirstElement(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Locate the first element matching one of the predicates, trying them in order.
+func firstOf(doc *html.Node, preds ...func(*html.Node) bool) *html.Node {
+	for _, p := range preds {
+		if n := FirstElement(doc, p); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// Matches elements whose attribute has one of the given values, ignoring case.
+func AttributeIs(key string, values ...string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		v := GetAttribute(n, key)
+		for _, want := range values {
+			if strings.EqualFold(v, want) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Matches elements having the given attribute, whatever its value.
+func AttributePresent(key string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		return hasAttribute(n, key)
+	}
+}
+
+// Matches elements whose attribute contains one of the given words, ignoring case.
+func AttributeContains(key string, words ...string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		v := strings.ToLower(GetAttribute(n, key))
+		if v == "" {
+			return false
+		}
+		for _, w := range words {
+			if strings.Contains(v, strings.ToLower(w)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Matches elements with one of the given classes.
+func HasClass(classes ...string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		for _, c := range strings.Fields(GetAttribute(n, "class")) {
+			for _, want := range classes {
+				if c == want {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// Longest text considered by the text predicates; bigger elements never match.
+const maxMatchedText = 300
+
+// Matches small elements whose whole text is one of the given texts, ignoring case.
+func TextIs(texts ...string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		text, ok := shortText(n)
+		if !ok || text == "" {
+			return false
+		}
+		for _, t := range texts {
+			if strings.EqualFold(text, t) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Matches small elements whose text contains one of the given phrases, ignoring case.
+func TextContains(phrases ...string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		text, ok := shortText(n)
+		if !ok {
+			return false
+		}
+		text = strings.ToLower(text)
+		for _, p := range phrases {
+			if strings.Contains(text, strings.ToLower(p)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// The visible text of the node with whitespace collapsed. Collecting stops early
+// and the boolean result is false if the text is longer than maxMatchedText.
+func shortText(n *html.Node) (string, bool) {
+	var sb strings.Builder
+	var collect func(*html.Node) bool
+	collect = func(n *html.Node) bool {
+		switch n.Type {
+		case html.TextNode:
+			sb.WriteString(n.Data)
+			sb.WriteByte(' ')
+			return sb.Len() <= 4*maxMatchedText
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style", "noscript":
+				return true
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if !collect(c) {
+				return false
+			}
+		}
+		return true
+	}
+	if !collect(n) {
+		return "", false
+	}
+	text := strings.Join(strings.Fields(sb.String()), " ")
+	return text, len(text) <= maxMatchedText
+}
+
+// Matches elements with one of the given tags.
+func TagIs(tags ...string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		for _, t := range tags {
+			if n.Data == t {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Matches elements for which all given predicates match.
+func All(preds ...func(*html.Node) bool) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		for _, p := range preds {
+			if !p(n) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Matches elements for which any of the given predicates matches.
+func Any(preds ...func(*html.Node) bool) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		for _, p := range preds {
+			if p(n) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"golang.org/x/net/html"
+)
+
+// Profile for posts clipped from sites published with Ghost.
+var ghostProfile = Profile{
+	Name:        "ghost",
+	Description: "Ghost posts: removes member sign-up cards, share buttons and comments.",
+	Detect:      func(doc *html.Node) bool { return DetectSite(doc) == "ghost" },
+	Strategies:  []string{"ghost", "article"},
+	Removals: []RemovalRule{
+		{
+			Description: "member sign-up and upgrade prompts",
+			Match: Any(
+				HasClass("footer-cta", "gh-subscribe", "kg-signup-card", "gh-post-upgrade-cta", "gh-cta"),
+				AttributePresent("data-members-form"),
+				TagIs("form"),
+			),
+		},
+		{
+			Description: "share buttons",
+			Match:       Any(HasClass("kg-share", "gh-share", "article-share"), TagIs("button")),
+		},
+		{
+			Description: "comments",
+			Match:       HasClass("article-comments", "gh-comments"),
+		},
+	},
+	SiteName: HasClass("gh-head-logo", "site-title"),
+}
+
+func init() {
+	RegisterStrategy(Strategy{
+		Name:        "ghost",
+		Description: "The article of a Ghost page or its content section.",
+		Locate: func(doc *html.Node) *html.Node {
+			return firstOf(doc,
+				All(TagIs("article"), HasClass("gh-article", "article", "post-full")),
+				HasClass("gh-content", "post-full-content"))
+		},
+	})
+	RegisterProfile(ghostProfile)
+}
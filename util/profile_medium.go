@@ -0,0 +1,74 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"golang.org/x/net/html"
+)
+
+// Profile for stories clipped from Medium and Medium hosted publications.
+var mediumProfile = Profile{
+	Name:        "medium",
+	Description: "Medium stories: removes follow, clap, listen and share widgets, badges and sign-up prompts.",
+	Detect:      func(doc *html.Node) bool { return DetectSite(doc) == "medium" },
+	Strategies:  []string{"article"},
+	Removals: []RemovalRule{
+		{
+			Description: "interactive buttons like clap, responses, bookmark, listen and share",
+			Match: Any(
+				TagIs("button"),
+				AttributeIs("role", "button"),
+				AttributeIs("data-testid",
+					"headerClapButton", "footerClapButton",
+					"headerSocialShareButton", "footerSocialShareButton",
+					"headerBookmarkButton", "footerBookmarkButton",
+					"audioPlayButton", "followButton"),
+			),
+		},
+		{
+			Description: "widgets labelled for screen readers",
+			Match: AttributeIs("aria-label",
+				"clap", "responses", "Share", "Listen", "Follow", "Subscribe",
+				"Add to list", "Bookmark", "More options", "Member-only story"),
+		},
+		{
+			Description: "clap and response counters",
+			Match:       HasClass("pw-multi-vote-icon", "pw-multi-vote-count", "pw-responses-count"),
+		},
+		{
+			Description: "member-only badge and remaining widget labels",
+			Match:       All(TagIs("div", "span", "a"), TextIs("Member-only story", "Follow", "Listen", "Share")),
+		},
+		{
+			Description: "sign-up prompts",
+			Match: TextContains(
+				"Sign up to discover human stories",
+				"Get unlimited access to the best of Medium",
+				"Become a member to read this story",
+			),
+		},
+	},
+}
+
+func init() {
+	RegisterProfile(mediumProfile)
+}
@@ -0,0 +1,67 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"golang.org/x/net/html"
+)
+
+// Profile for posts clipped from Substack newsletters, including custom domains.
+var substackProfile = Profile{
+	Name:        "substack",
+	Description: "Substack posts: removes subscribe forms, like and share bars and comment threads.",
+	Detect:      func(doc *html.Node) bool { return DetectSite(doc) == "substack" },
+	Strategies:  []string{"substack"},
+	Removals: []RemovalRule{
+		{
+			Description: "subscribe forms and prompts",
+			Match: Any(
+				HasClass("subscription-widget-wrap", "subscription-widget", "subscribe-widget",
+					"subscribe-footer", "paywall", "paywall-jump"),
+				TagIs("form"),
+			),
+		},
+		{
+			Description: "like, comment and share bars",
+			Match:       Any(HasClass("post-ufi", "post-footer", "share-dialog", "post-header-actions"), TagIs("button")),
+		},
+		{
+			Description: "comment threads",
+			Match:       Any(HasClass("comments-section", "comments-page"), AttributeIs("id", "discussion")),
+		},
+	},
+	TitleSuffixes: []string{`\s+-\s+by\s+.*$`},
+}
+
+func init() {
+	RegisterStrategy(Strategy{
+		Name:        "substack",
+		Description: "The post of a Substack page or its body.",
+		Locate: func(doc *html.Node) *html.Node {
+			return firstOf(doc,
+				All(TagIs("article"), HasClass("post")),
+				HasClass("available-content"),
+				All(HasClass("body"), HasClass("markup")))
+		},
+	})
+	RegisterProfile(substackProfile)
+}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func parseFixture(t *testing.T, name string) *html.Node {
+	t.Helper()
+	file, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = file.Close() }()
+	doc, err := html.Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// Apply the profile to the fixture the way the shrink command does.
+func applyProfile(t *testing.T, doc *html.Node, p Profile) *html.Node {
+	t.Helper()
+	using, err := LookupStrategies(p.Strategies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _, found := LocateContent(doc, using)
+	if !found {
+		t.Fatalf("profile %s does not locate the content", p.Name)
+	}
+	PruneAround(root)
+	ApplyRemovals(root, p.Removals)
+	return root
+}
+
+func TestBuiltinProfiles(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		profile   string
+		wantTitle string
+		kept      []string
+		removed   []string
+	}{
+		{"medium.html", "medium", "Writing Table Driven Tests in Go",
+			[]string{
+				"Writing Table Driven Tests in Go",
+				"Jane Doe",
+				"5 min read",
+				"Table driven tests keep test cases compact and readable.",
+				"tests := []struct{ name string }{}",
+				"Each case is a row in a table and runs as a subtest.",
+				"Golang",
+			},
+			[]string{"Member-only story", "Follow", "1.2K", "14", "Listen", "Share", "Sign up to discover", "More from Jane Doe", "Sign in"}},
+		{"substack.html", "substack", "Why Boring Tech Wins",
+			[]string{
+				"Why Boring Tech Wins",
+				"Stability beats novelty",
+				"Sam Writer",
+				"Choosing well known technology lets a small team move fast.",
+				"Boring tools have documented failure modes.",
+			},
+			[]string{"Subscribe", "Thanks for reading", "42", "Share", "Discussion about this post", "Sign in"}},
+		{"ghost.html", "ghost", "Observability on a Budget",
+			[]string{
+				"Observability on a Budget",
+				"Kim Ops",
+				"Start with logs you already have before buying another tool.",
+				"Sample traces instead of storing every span.",
+			},
+			[]string{"Join Ops Notes", "Subscribe", "Share on Twitter", "Comments are for members only", "Read more", "Sign in"}},
+		{"wordpress.html", "wordpress", "Baking Sourdough at Home",
+			[]string{
+				"Baking Sourdough at Home",
+				"Feed the starter twelve hours before mixing the dough.",
+				"Bake in a preheated dutch oven for the best crust.",
+				"Posted in Bread",
+			},
+			[]string{"Subscribe", "Share this", "Related", "Previous post", "thoughts on this", "Proudly powered"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			doc := parseFixture(t, tt.fixture)
+			p, ok := DetectProfile(doc)
+			if !ok || p.Name != tt.profile {
+				t.Fatalf("DetectProfile() = %q, %v, want %s", p.Name, ok, tt.profile)
+			}
+			if title, _ := ResolveTitle(doc); title.Title != tt.wantTitle {
+				t.Errorf("ResolveTitle() = %q, want %q", title.Title, tt.wantTitle)
+			}
+			// the profile finds the site name to strip from the title even without og:site_name
+			if siteName := FirstElement(doc, All(TagIs("meta"), AttributeIs("property", "og:site_name"))); siteName != nil {
+				siteName.Parent.RemoveChild(siteName)
+			}
+			if title := CleanTitle(TextContent(FindElement(doc, "title")), DetectSite(doc), SiteNames(doc)...); title != tt.wantTitle {
+				t.Errorf("CleanTitle() without og:site_name = %q, want %q", title, tt.wantTitle)
+			}
+			root := applyProfile(t, doc, p)
+			text := strings.Join(strings.Fields(TextContent(root)), " ")
+			for _, kept := range tt.kept {
+				if !strings.Contains(text, kept) {
+					t.Errorf("profile %s removed %q", p.Name, kept)
+				}
+			}
+			for _, removed := range tt.removed {
+				if strings.Contains(text, removed) {
+					t.Errorf("profile %s kept %q in %q", p.Name, removed, text)
+				}
+			}
+			if n := len(findAll(root, "button")) + len(findAll(root, "form")); n != 0 {
+				t.Errorf("profile %s kept %d buttons or forms", p.Name, n)
+			}
+		})
+	}
+}
+
+func TestLookupProfile_Unknown(t *testing.T) {
+	if _, err := LookupProfile("myspace"); err == nil {
+		t.Error("LookupProfile() expected an error for an unknown profile")
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Profile for posts clipped from WordPress sites using common themes.
+var wordpressProfile = Profile{
+	Name:        "wordpress",
+	Description: "WordPress posts: removes share bars, related posts, subscription forms and comments.",
+	Detect:      func(doc *html.Node) bool { return DetectSite(doc) == "wordpress" || usesWordPressAssets(doc) },
+	Strategies:  []string{"wordpress", "article"},
+	Removals: []RemovalRule{
+		{
+			Description: "share bars",
+			Match: HasClass("sharedaddy", "sd-sharing-enabled", "addtoany_share_save_container",
+				"social-share", "share-buttons", "wp-block-jetpack-sharing-buttons"),
+		},
+		{
+			Description: "related posts and post navigation",
+			Match:       Any(HasClass("jp-relatedposts", "related-posts", "post-navigation", "nav-links"), AttributeIs("id", "jp-relatedposts")),
+		},
+		{
+			Description: "subscription forms",
+			Match:       Any(HasClass("wp-block-jetpack-subscriptions", "jetpack_subscription_widget", "newsletter-signup"), TagIs("form")),
+		},
+		{
+			Description: "comments",
+			Match:       Any(HasClass("comments-area", "comment-respond"), AttributeIs("id", "comments", "respond")),
+		},
+		{
+			Description: "advertisements",
+			Match:       HasClass("wpcnt", "wordads-ad-wrapper"),
+		},
+	},
+	SiteName: HasClass("site-title"),
+}
+
+// Reports whether the document loads assets from the usual WordPress locations.
+func usesWordPressAssets(doc *html.Node) bool {
+	return FirstElement(doc, func(n *html.Node) bool {
+		for _, key := range []string{"href", "src"} {
+			if v := GetAttribute(n, key); strings.Contains(v, "/wp-content/") || strings.Contains(v, "/wp-includes/") {
+				return true
+			}
+		}
+		return false
+	}) != nil
+}
+
+func init() {
+	RegisterStrategy(Strategy{
+		Name:        "wordpress",
+		Description: "The post article of a WordPress page or its entry content.",
+		Locate: func(doc *html.Node) *html.Node {
+			return firstOf(doc,
+				All(TagIs("article"), Any(HasClass("post", "type-post", "hentry"), func(n *html.Node) bool {
+					return strings.HasPrefix(GetAttribute(n, "id"), "post-")
+				})),
+				HasClass("entry-content", "post-content"))
+		},
+	})
+	RegisterProfile(wordpressProfile)
+}
@@ -0,0 +1,112 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// A problem with the content of a clipping which makes the output questionable.
+type ContentFlag string
+
+const (
+	// Only the teaser of a paywalled article was clipped.
+	FlagPaywalled ContentFlag = "paywalled"
+	// The article has hardly any text, e.g. because it was saved before it was rendered.
+	FlagNearEmpty ContentFlag = "near-empty"
+)
+
+// Articles declared as not accessible for free are considered truncated if they
+// have fewer words.
+const teaserWords = 300
+
+// Elements prompting to subscribe or sign in to read the rest of an article.
+var paywallPrompt = Any(
+	HasClass("paywall", "paywall-cta", "gh-post-upgrade-cta", "pmpro_content_message", "mepr-unauthorized-message"),
+	AttributeIs("data-testid", "paywall"),
+	TextContains(
+		"The author made this story available to Medium members only",
+		"Read the full story with a free account",
+		"Create an account to read the full story",
+		"This post is for paid subscribers",
+		"This post is for subscribers only",
+		"Keep reading with a 7-day free trial",
+		"Subscribe to continue reading",
+		"Subscribe to keep reading",
+		"This content is for members only",
+	),
+)
+
+// Reports whether the clipping holds only the teaser of a paywalled article: the page
+// prompts to subscribe or sign in to read on, or it declares the article as not
+// accessible for free while the content root holds just a few words.
+// Must be applied to the original document, before prompts are removed.
+func DetectPaywall(doc, root *html.Node) bool {
+	if FirstElement(doc, paywallPrompt) != nil {
+		return true
+	}
+	return !accessibleForFree(doc) && root != nil && len(strings.Fields(TextContent(root))) < teaserWords
+}
+
+// Reports whether the JSON-LD of the document does not declare the article as
+// not accessible for free.
+func accessibleForFree(doc *html.Node) bool {
+	for _, obj := range JSONLDObjects(doc) {
+		switch v := obj["isAccessibleForFree"].(type) {
+		case bool:
+			if !v {
+				return false
+			}
+		case string:
+			if strings.EqualFold(v, "false") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Record the flags in the document as <meta name="shrinkr:flags">, replacing the
+// flags recorded before.
+func AddContentFlags(doc *html.Node, flags []ContentFlag) {
+	head := FindElement(doc, "head")
+	if head == nil {
+		return
+	}
+	for c := head.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && c.Data == "meta" && GetAttribute(c, "name") == "shrinkr:flags" {
+			head.RemoveChild(c)
+		}
+		c = next
+	}
+	if len(flags) == 0 {
+		return
+	}
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = string(f)
+	}
+	head.AppendChild(newNamedMeta("shrinkr:flags", strings.Join(names, " ")))
+}
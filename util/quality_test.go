@@ -0,0 +1,74 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectPaywall(t *testing.T) {
+	notFree := `<script type="application/ld+json">{"@type":"NewsArticle","isAccessibleForFree":false}</script>`
+	long := strings.Repeat("word ", teaserWords)
+	tests := []struct {
+		name string
+		doc  string
+		want bool
+	}{
+		{"medium prompt", `<article><p>Teaser</p></article><div><h2>The author made this story available to Medium members only.</h2></div>`, true},
+		{"substack paywall", `<article><p>Teaser</p><div class="paywall"><h2>Keep reading</h2></div></article>`, true},
+		{"not free and short", notFree + `<article><p>Teaser</p></article>`, true},
+		{"not free but complete", notFree + `<article><p>` + long + `</p></article>`, false},
+		{"free article", `<article><p>Teaser</p><section>Comments are for members only.</section></article>`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := mustParse(t, tt.doc)
+			if got := DetectPaywall(doc, FindElement(doc, "article")); got != tt.want {
+				t.Errorf("DetectPaywall() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	for _, name := range []string{"medium.html", "substack.html", "ghost.html", "wordpress.html"} {
+		doc := parseFixture(t, name)
+		root, _, _ := LocateContent(doc, []Strategy{strategies["article"]})
+		if root != nil && DetectPaywall(doc, root) {
+			t.Errorf("DetectPaywall() flagged fixture %s", name)
+		}
+	}
+}
+
+func TestAddContentFlags(t *testing.T) {
+	doc := mustParse(t, `<html><head></head><body></body></html>`)
+	AddContentFlags(doc, []ContentFlag{FlagPaywalled, FlagNearEmpty})
+	if got := MetaContent(doc, "shrinkr:flags"); got != "paywalled near-empty" {
+		t.Errorf("AddContentFlags() wrote %q", got)
+	}
+	AddContentFlags(doc, []ContentFlag{FlagNearEmpty})
+	if got := MetaContents(doc, "shrinkr:flags"); len(got) != 1 || got[0] != "near-empty" {
+		t.Errorf("AddContentFlags() again wrote %q", got)
+	}
+	AddContentFlags(doc, nil)
+	if got := MetaContents(doc, "shrinkr:flags"); len(got) != 0 {
+		t.Errorf("AddContentFlags() without flags kept %q", got)
+	}
+}
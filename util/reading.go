@@ -0,0 +1,157 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// Average reading speed used to estimate the reading time.
+const WordsPerMinute = 230
+
+// Minimum number of words needed to detect the language of a text.
+const minDetectionWords = 20
+
+// Size, reading time and language of the text of an article.
+type ReadingInfo struct {
+	Words          int    `json:"words"`
+	ReadingMinutes int    `json:"readingMinutes"`
+	Language       string `json:"language,omitempty"`
+}
+
+// Frequent trigrams of the languages recognized by DetectLanguage; a space marks the
+// start or end of a word.
+var languageTrigrams = map[string][]string{
+	"en": {" th", "the", "he ", "and", " an", "nd ", " of", "of ", "ing", "ng ", " to", "to ", " is", "is ", "hat", "tha", " wi", "ith", "you", " yo"},
+	"de": {"en ", "er ", " de", "der", "die", " di", "ie ", "ein", " ei", "ich", "sch", "che", "und", " un", "cht", "den", "ung", "gen", "ist", " is"},
+	"fr": {" de", "es ", "de ", " le", "le ", "les", "ent", " la", "la ", "que", " qu", "ue ", " et", "et ", "des", " pa", "our", " un", "ne ", "ait"},
+	"es": {" de", "de ", "os ", " la", "la ", " el", "el ", "que", " qu", "ue ", " en", "en ", "as ", "los", " lo", "ión", "ado", " co", " se", "por"},
+	"it": {" di", "di ", "che", " ch", " la", "la ", "to ", "re ", "ell", "lla", " il", "il ", "per", " pe", "one", "zio", "are", "del", " co", "ent"},
+	"pt": {" de", "de ", "os ", "que", " qu", "ue ", " a ", "do ", "da ", "ão ", "ção", " co", "em ", "as ", " pa", " se", "nte", " e ", "um ", "ara"},
+	"nl": {"en ", " de", "de ", "van", " va", "an ", "het", " he", "et ", "een", " ee", "er ", "ijk", "oor", " in", "ver", " ve", "aar", "den", " zi"},
+}
+
+// Count the words of the text below root, estimate the reading time and determine the
+// language, declared by lang attributes or else detected from the text.
+func MeasureReading(root *html.Node) ReadingInfo {
+	text := TextContent(root)
+	words := len(strings.Fields(text))
+	r := ReadingInfo{
+		Words:          words,
+		ReadingMinutes: int(math.Ceil(float64(words) / WordsPerMinute)),
+		Language:       DeclaredLanguage(root),
+	}
+	if r.Language == "" {
+		r.Language = DetectLanguage(text)
+	}
+	return r
+}
+
+// The primary language declared by the lang attribute of the element or its nearest
+// ancestor having one, in lower case. Empty if none is declared.
+func DeclaredLanguage(n *html.Node) string {
+	for ; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		for _, key := range []string{"lang", "xml:lang"} {
+			if lang := strings.TrimSpace(GetAttribute(n, key)); lang != "" {
+				primary, _, _ := strings.Cut(lang, "-")
+				primary, _, _ = strings.Cut(primary, "_")
+				return strings.ToLower(primary)
+			}
+		}
+	}
+	return ""
+}
+
+// Detect the language of the text by the frequency of typical trigrams. Recognizes
+// English, German, French, Spanish, Italian, Portuguese and Dutch. Returns an empty
+// string if the text is too short or no language is clearly ahead.
+func DetectLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) < minDetectionWords {
+		return ""
+	}
+	counts := map[string]int{}
+	for _, w := range words {
+		runes := []rune(" " + w + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts[string(runes[i:i+3])]++
+		}
+	}
+	type score struct {
+		lang  string
+		value int
+	}
+	var scores []score
+	for lang, trigrams := range languageTrigrams {
+		s := score{lang: lang}
+		for _, t := range trigrams {
+			s.value += counts[t]
+		}
+		scores = append(scores, s)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].value != scores[j].value {
+			return scores[i].value > scores[j].value
+		}
+		return scores[i].lang < scores[j].lang
+	})
+	// the best language must be clearly ahead of the second one
+	if scores[0].value == 0 || float64(scores[0].value) < 1.2*float64(scores[1].value) {
+		return ""
+	}
+	return scores[0].lang
+}
+
+// Record the reading information in the document: the word count and reading time as
+// meta tags and the language as lang attribute of <html> unless it declares one.
+func AddReadingInfo(doc *html.Node, r ReadingInfo) {
+	if root := FindElement(doc, "html"); root != nil && r.Language != "" && GetAttribute(root, "lang") == "" {
+		setAttribute(root, "lang", r.Language)
+	}
+	head := FindElement(doc, "head")
+	if head == nil {
+		return
+	}
+	for c := head.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && c.Data == "meta" && readingMetaNames[GetAttribute(c, "name")] {
+			head.RemoveChild(c)
+		}
+		c = next
+	}
+	head.AppendChild(newNamedMeta("shrinkr:words", strconv.Itoa(r.Words)))
+	head.AppendChild(newNamedMeta("shrinkr:reading-minutes", strconv.Itoa(r.ReadingMinutes)))
+}
+
+// Names of the meta tags written by AddReadingInfo.
+var readingMetaNames = map[string]bool{"shrinkr:words": true, "shrinkr:reading-minutes": true}
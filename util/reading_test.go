@@ -0,0 +1,71 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		lang string
+		text string
+	}{
+		{"en", "The quick brown fox jumps over the lazy dog. This is a sentence that shows how the detection of the language works with English text and all of its typical words."},
+		{"de", "Der schnelle braune Fuchs springt über den faulen Hund. Dies ist ein Satz, der zeigt, wie die Erkennung der Sprache mit einem deutschen Text und seinen typischen Wörtern funktioniert."},
+		{"fr", "Le renard brun rapide saute par-dessus le chien paresseux. Ceci est une phrase qui montre comment la détection de la langue fonctionne avec un texte français et les mots typiques."},
+		{"es", "El rápido zorro marrón salta sobre el perro perezoso. Esta es una frase que muestra cómo funciona la detección del idioma con un texto en español y todas las palabras típicas de los textos."},
+		{"it", "La veloce volpe marrone salta sopra il cane pigro. Questa è una frase che mostra come funziona il riconoscimento della lingua con un testo italiano e le parole tipiche della lingua."},
+		{"pt", "A rápida raposa marrom pula sobre o cão preguiçoso. Esta é uma frase que mostra como funciona a detecção do idioma com um texto em português e as palavras típicas da língua e da informação."},
+		{"nl", "De snelle bruine vos springt over de luie hond. Dit is een zin die laat zien hoe de herkenning van de taal werkt met een Nederlandse tekst en de typische woorden van het Nederlands."},
+		{"", "Too short to tell."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			if got := DetectLanguage(tt.text); got != tt.lang {
+				t.Errorf("DetectLanguage() = %q, want %q", got, tt.lang)
+			}
+		})
+	}
+}
+
+func TestMeasureReading(t *testing.T) {
+	words := strings.Repeat("word ", 500)
+	doc := mustParse(t, `<html lang="en-GB"><head></head><body><article><p>`+words+`</p><script>var x = 1;</script></article></body></html>`)
+	r := MeasureReading(FindElement(doc, "article"))
+	if r != (ReadingInfo{Words: 500, ReadingMinutes: 3, Language: "en"}) {
+		t.Errorf("MeasureReading() = %+v", r)
+	}
+	if got := DeclaredLanguage(FindElement(mustParse(t, `<p>Text</p>`), "p")); got != "" {
+		t.Errorf("DeclaredLanguage() = %q for a document without lang", got)
+	}
+}
+
+func TestAddReadingInfo(t *testing.T) {
+	doc := mustParse(t, `<html><head><meta name="shrinkr:words" content="1"></head><body></body></html>`)
+	AddReadingInfo(doc, ReadingInfo{Words: 1200, ReadingMinutes: 6, Language: "de"})
+	want := `<html lang="de"><head><meta name="shrinkr:words" content="1200"/><meta name="shrinkr:reading-minutes" content="6"/></head><body></body></html>`
+	if got := renderNode(t, FindElement(doc, "html")); got != want {
+		t.Errorf("AddReadingInfo() =\n%s\nwant\n%s", got, want)
+	}
+}
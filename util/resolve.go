@@ -0,0 +1,188 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Attributes holding a single URL, by element.
+var urlAttributes = map[string][]string{
+	"a":      {"href"},
+	"area":   {"href"},
+	"link":   {"href"},
+	"img":    {"src"},
+	"script": {"src"},
+	"iframe": {"src"},
+	"source": {"src"},
+	"video":  {"src", "poster"},
+	"audio":  {"src"},
+	"embed":  {"src"},
+}
+
+// Determine the URL relative URLs of the document are resolved against.
+// An explicitly given URL takes precedence over <base href>, which takes precedence
+// over the canonical URL. Returns nil if none of them is an absolute URL.
+func DocumentBaseURL(doc *html.Node, explicit, canonical string) *url.URL {
+	var base *url.URL
+	if c, err := url.Parse(canonical); err == nil && c.IsAbs() {
+		base = c
+	}
+	if b := FindElement(doc, "base"); b != nil {
+		if href := strings.TrimSpace(GetAttribute(b, "href")); href != "" {
+			if u, err := url.Parse(href); err == nil {
+				if u.IsAbs() {
+					base = u
+				} else if base != nil {
+					base = base.ResolveReference(u)
+				}
+			}
+		}
+	}
+	if e, err := url.Parse(explicit); err == nil && e.IsAbs() {
+		base = e
+	}
+	return base
+}
+
+// Resolve all relative URLs in href, src and srcset attributes against the given base.
+// Links to anchors within the document are kept. Returns the number of URLs resolved.
+func ResolveURLs(doc *html.Node, base *url.URL) int {
+	if base == nil {
+		return 0
+	}
+	resolved := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			keys := urlAttributes[n.Data]
+			for i, a := range n.Attr {
+				switch {
+				case a.Key == "srcset":
+					var changed int
+					n.Attr[i].Val, changed = resolveSrcset(a.Val, base)
+					resolved += changed
+				case slices.Contains(keys, a.Key):
+					if abs, ok := resolveURL(a.Val, base); ok {
+						n.Attr[i].Val = abs
+						resolved++
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return resolved
+}
+
+// Resolve a single URL. The boolean result is false if the URL was left unchanged.
+func resolveURL(raw string, base *url.URL) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.HasPrefix(raw, "#") {
+		return raw, false
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.IsAbs() {
+		return raw, false
+	}
+	return base.ResolveReference(u).String(), true
+}
+
+// Resolve the URLs of all candidates of a srcset attribute.
+func resolveSrcset(srcset string, base *url.URL) (string, int) {
+	resolved := 0
+	candidates := ParseSrcset(srcset)
+	for i, c := range candidates {
+		if abs, ok := resolveURL(c.URL, base); ok {
+			candidates[i].URL = abs
+			resolved++
+		}
+	}
+	if resolved == 0 {
+		return srcset, 0
+	}
+	return FormatSrcset(candidates), resolved
+}
+
+// A single image candidate of a srcset attribute.
+type SrcsetCandidate struct {
+	URL        string
+	Descriptor string
+}
+
+// Split a srcset attribute into its candidates following the HTML parsing rules:
+// a URL runs up to the next whitespace and may contain commas, except for trailing
+// ones, the descriptors run up to the next comma outside of parentheses.
+func ParseSrcset(srcset string) []SrcsetCandidate {
+	var candidates []SrcsetCandidate
+	isSpace := func(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' }
+	i := 0
+	for i < len(srcset) {
+		for i < len(srcset) && (isSpace(srcset[i]) || srcset[i] == ',') {
+			i++
+		}
+		start := i
+		for i < len(srcset) && !isSpace(srcset[i]) {
+			i++
+		}
+		if start == i {
+			break
+		}
+		u := srcset[start:i]
+		var descriptor string
+		if trimmed := strings.TrimRight(u, ","); trimmed != u {
+			u = trimmed
+		} else {
+			start, depth := i, 0
+			for ; i < len(srcset); i++ {
+				if c := srcset[i]; c == '(' {
+					depth++
+				} else if c == ')' && depth > 0 {
+					depth--
+				} else if c == ',' && depth == 0 {
+					break
+				}
+			}
+			descriptor = strings.Join(strings.Fields(srcset[start:i]), " ")
+		}
+		if u != "" {
+			candidates = append(candidates, SrcsetCandidate{URL: u, Descriptor: descriptor})
+		}
+	}
+	return candidates
+}
+
+// Join srcset candidates into an attribute value.
+func FormatSrcset(candidates []SrcsetCandidate) string {
+	parts := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		parts = append(parts, strings.TrimSpace(c.URL+" "+c.Descriptor))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,118 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"testing"
+)
+
+func TestDocumentBaseURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       string
+		explicit  string
+		canonical string
+		want      string
+	}{
+		{"canonical", `<head></head>`, "", "https://example.com/blog/post", "https://example.com/blog/post"},
+		{"base wins over canonical", `<head><base href="https://cdn.example.com/"></head>`, "", "https://example.com/blog/post", "https://cdn.example.com/"},
+		{"relative base", `<head><base href="/assets/"></head>`, "", "https://example.com/blog/post", "https://example.com/assets/"},
+		{"explicit wins", `<head><base href="https://cdn.example.com/"></head>`, "https://other.org/", "https://example.com/", "https://other.org/"},
+		{"nothing", `<head></head>`, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DocumentBaseURL(mustParse(t, tt.doc), tt.explicit, tt.canonical)
+			if (got == nil && tt.want != "") || (got != nil && got.String() != tt.want) {
+				t.Errorf("DocumentBaseURL() = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveURLs(t *testing.T) {
+	doc := mustParse(t, `<body><a href="/@jane">Jane</a><a href="#section">Jump</a>`+
+		`<img src="img/a.png" srcset="img/a-1x.png 1x, https://cdn.example.com/a-2x.png 2x">`+
+		`<a href="mailto:x@example.com">Mail</a></body>`)
+	base := DocumentBaseURL(doc, "", "https://medium.com/@jane/post-1")
+	if n := ResolveURLs(doc, base); n != 3 {
+		t.Errorf("ResolveURLs() resolved %d URLs, want 3", n)
+	}
+	links := []string{}
+	for a := FindElement(doc, "a"); a != nil; a = a.NextSibling {
+		if a.Data == "a" {
+			links = append(links, GetAttribute(a, "href"))
+		}
+	}
+	want := []string{"https://medium.com/@jane", "#section", "mailto:x@example.com"}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("ResolveURLs() links = %v, want %v", links, want)
+		}
+	}
+	img := FindElement(doc, "img")
+	if got := GetAttribute(img, "src"); got != "https://medium.com/@jane/img/a.png" {
+		t.Errorf("ResolveURLs() src = %v", got)
+	}
+	if got, want := GetAttribute(img, "srcset"), "https://medium.com/@jane/img/a-1x.png 1x, https://cdn.example.com/a-2x.png 2x"; got != want {
+		t.Errorf("ResolveURLs() srcset = %v, want %v", got, want)
+	}
+}
+
+func TestParseSrcset(t *testing.T) {
+	tests := []struct {
+		name   string
+		srcset string
+		want   []SrcsetCandidate
+	}{
+		{"plain", "a-1x.png 1x, a-2x.png 2x",
+			[]SrcsetCandidate{{"a-1x.png", "1x"}, {"a-2x.png", "2x"}}},
+		{"commas in URL", "https://res.example.com/upload/w_640,c_limit/a.jpg 640w, https://res.example.com/upload/w_1400,c_limit/a.jpg 1400w",
+			[]SrcsetCandidate{{"https://res.example.com/upload/w_640,c_limit/a.jpg", "640w"}, {"https://res.example.com/upload/w_1400,c_limit/a.jpg", "1400w"}}},
+		{"data URL", "data:image/png;base64,iVBORw0KGgo= 1x, b.png 2x",
+			[]SrcsetCandidate{{"data:image/png;base64,iVBORw0KGgo=", "1x"}, {"b.png", "2x"}}},
+		{"no descriptors", "a.png, b.png 2x,",
+			[]SrcsetCandidate{{"a.png", ""}, {"b.png", "2x"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseSrcset(tt.srcset)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseSrcset() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ParseSrcset() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveURLs_srcsetWithCommas(t *testing.T) {
+	doc := mustParse(t, `<img srcset="data:image/png;base64,iVBORw0KGgo= 1x, img/w_640,c_limit/a.jpg 2x">`)
+	ResolveURLs(doc, DocumentBaseURL(doc, "https://example.com/post/", ""))
+	want := "data:image/png;base64,iVBORw0KGgo= 1x, https://example.com/post/img/w_640,c_limit/a.jpg 2x"
+	if got := GetAttribute(FindElement(doc, "img"), "srcset"); got != want {
+		t.Errorf("ResolveURLs() srcset = %v, want %v", got, want)
+	}
+}
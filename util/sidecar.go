@@ -0,0 +1,141 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Description of a shrinked document, written next to it for indexing.
+type Sidecar struct {
+	Source       string    `json:"source"`
+	SourceSHA256 string    `json:"sourceSha256"`
+	SourceSize   int64     `json:"sourceSize"`
+	Output       string    `json:"output"`
+	OutputSize   int64     `json:"outputSize"`
+	CanonicalURL string    `json:"canonicalUrl,omitempty"`
+	Metadata     Metadata  `json:"metadata"`
+	Strategy     string    `json:"strategy"`
+	Version      string    `json:"shrinkrVersion"`
+	Processed    time.Time `json:"processed"`
+	// Bytes removed per category of content, e.g. "scripts".
+	RemovedBytes map[string]int64 `json:"removedBytes"`
+	// Bytes removed by each cleanup pass.
+	RemovedByPass map[string]int64 `json:"removedByPass,omitempty"`
+	Warnings      []string         `json:"warnings,omitempty"`
+	// Words, reading time and language of the output.
+	Reading ReadingInfo `json:"reading"`
+	// Problems found with the content, e.g. a paywall.
+	Flags []ContentFlag `json:"flags,omitempty"`
+	// Tags from the metadata and keywords extracted from the text.
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// Determine the bytes removed per category by comparing the breakdown of the
+// original document with the one of the shrinked document.
+func RemovedBytes(before, after SizeBreakdown) map[string]int64 {
+	return map[string]int64{
+		"total":    before.Total - after.Total,
+		"head":     before.Head - after.Head,
+		"scripts":  before.Scripts - after.Scripts,
+		"styles":   before.Styles - after.Styles,
+		"images":   before.Images - after.Images,
+		"article":  before.Article - after.Article,
+		"siblings": before.Removable - after.Removable,
+	}
+}
+
+// What is known about a shrinked document when its sidecar is written.
+type SidecarInput struct {
+	// Name of the input file.
+	Source string
+	// Size and hash of the source, version and time of processing.
+	Provenance Provenance
+	Output     string
+	OutputSize int64
+	Metadata   Metadata
+	Strategy   string
+	// Sizes of the document before and after the cleanup passes.
+	Before, After SizeBreakdown
+	RemovedByPass map[string]int64
+	Reading       ReadingInfo
+	Flags         []ContentFlag
+	Keywords      []string
+	Warnings      []string
+}
+
+// Describe the shrinked document for the sidecar file.
+func NewSidecar(in SidecarInput) Sidecar {
+	return Sidecar{
+		Source:        in.Source,
+		SourceSHA256:  in.Provenance.OriginalSHA256,
+		SourceSize:    in.Provenance.OriginalSize,
+		Output:        in.Output,
+		OutputSize:    in.OutputSize,
+		CanonicalURL:  in.Metadata.CanonicalURL,
+		Metadata:      in.Metadata,
+		Strategy:      in.Strategy,
+		Version:       in.Provenance.Version,
+		Processed:     in.Provenance.Processed,
+		RemovedBytes:  RemovedBytes(in.Before, in.After),
+		RemovedByPass: in.RemovedByPass,
+		Warnings:      in.Warnings,
+		Reading:       in.Reading,
+		Flags:         in.Flags,
+		Keywords:      in.Keywords,
+	}
+}
+
+// Name of the sidecar file belonging to the given output file: the output with
+// the extension .json.
+func SidecarName(output string) string {
+	return strings.TrimSuffix(output, filepath.Ext(output)) + ".json"
+}
+
+// Write the sidecar next to its output file. Fails if the output itself has the
+// extension .json, as the sidecar would replace it.
+func WriteSidecar(s Sidecar) error {
+	if name := SidecarName(s.Output); name == s.Output {
+		return fmt.Errorf("sidecar %s would replace the output", name)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(SidecarName(s.Output), append(data, '\n'), 0o644)
+}
+
+// Read the sidecar belonging to the given output file.
+func ReadSidecar(output string) (Sidecar, error) {
+	var s Sidecar
+	data, err := os.ReadFile(SidecarName(output))
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
@@ -0,0 +1,123 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSidecarName(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"out/Title.html", "out/Title.json"},
+		{"out/Go 1.22. What's new.html", "out/Go 1.22. What's new.json"},
+		{"noext", "noext.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			if got := SidecarName(tt.output); got != tt.want {
+				t.Errorf("SidecarName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSidecar(t *testing.T) {
+	processed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	metadata := Metadata{Title: "T", CanonicalURL: "https://example.com/a"}
+	got := NewSidecar(SidecarInput{
+		Source:        "in.html",
+		Provenance:    Provenance{Version: "1.0.0", Source: "https://example.com/a", Processed: processed, OriginalSize: 1000, OriginalSHA256: "abc"},
+		Output:        "out.html",
+		OutputSize:    200,
+		Metadata:      metadata,
+		Strategy:      "article",
+		Before:        SizeBreakdown{Total: 1000, Scripts: 300},
+		After:         SizeBreakdown{Total: 200},
+		RemovedByPass: map[string]int64{"rewrite-head": 500},
+		Reading:       ReadingInfo{Words: 230, ReadingMinutes: 1, Language: "en"},
+		Flags:         []ContentFlag{FlagNearEmpty},
+		Keywords:      []string{"go"},
+		Warnings:      []string{"suspicious"},
+	})
+	want := Sidecar{
+		Source:        "in.html",
+		SourceSHA256:  "abc",
+		SourceSize:    1000,
+		Output:        "out.html",
+		OutputSize:    200,
+		CanonicalURL:  "https://example.com/a",
+		Metadata:      metadata,
+		Strategy:      "article",
+		Version:       "1.0.0",
+		Processed:     processed,
+		RemovedBytes:  RemovedBytes(SizeBreakdown{Total: 1000, Scripts: 300}, SizeBreakdown{Total: 200}),
+		RemovedByPass: map[string]int64{"rewrite-head": 500},
+		Warnings:      []string{"suspicious"},
+		Reading:       ReadingInfo{Words: 230, ReadingMinutes: 1, Language: "en"},
+		Flags:         []ContentFlag{FlagNearEmpty},
+		Keywords:      []string{"go"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewSidecar() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteSidecar_refusesJSONOutput(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "x.json")
+	if err := WriteSidecar(Sidecar{Output: output}); err == nil {
+		t.Errorf("WriteSidecar() for output %s succeeded", output)
+	}
+}
+
+func TestSidecar_RoundTrip(t *testing.T) {
+	want := Sidecar{
+		Source:       "in.html",
+		SourceSHA256: "abc",
+		SourceSize:   1000,
+		Output:       filepath.Join(t.TempDir(), "out.html"),
+		OutputSize:   200,
+		Metadata:     Metadata{Title: "T", Authors: []string{"A"}},
+		Strategy:     "article",
+		Version:      "1.0.0",
+		Processed:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		RemovedBytes: RemovedBytes(SizeBreakdown{Total: 1000, Scripts: 300}, SizeBreakdown{Total: 200}),
+	}
+	if err := WriteSidecar(want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadSidecar(want.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadSidecar() = %+v, want %+v", got, want)
+	}
+	if got.RemovedBytes["scripts"] != 300 || got.RemovedBytes["total"] != 800 {
+		t.Errorf("RemovedBytes = %v", got.RemovedBytes)
+	}
+}
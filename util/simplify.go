@@ -0,0 +1,175 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Options controlling the structural simplification.
+type SimplifyOptions struct {
+	// Attributes which do not prevent unwrapping or removing an element,
+	// e.g. class and style once the style sheets are gone.
+	IgnoredAttributes []string
+}
+
+// Number of changes made by Simplify.
+type SimplifyCounts struct {
+	Unwrapped int
+	Removed   int
+	Merged    int
+}
+
+// Elements which are meaningful even without content.
+var keepWhenEmpty = map[string]bool{
+	"img": true, "br": true, "hr": true, "wbr": true, "input": true, "iframe": true,
+	"video": true, "audio": true, "source": true, "track": true, "picture": true,
+	"svg": true, "canvas": true, "embed": true, "object": true, "textarea": true,
+	"td": true, "th": true, "tr": true, "col": true, "colgroup": true, "math": true,
+	"meta": true, "link": true, "script": true, "style": true, "title": true,
+	"head": true, "body": true, "html": true, "article": true, "main": true,
+	// removing an item renumbers the items of an ordered list following it
+	"li": true,
+}
+
+// Block elements; whitespace inside them does not contribute to the rendered text.
+var blockElements = map[string]bool{
+	"div": true, "section": true, "article": true, "main": true, "p": true, "pre": true,
+	"blockquote": true, "figure": true, "figcaption": true, "ul": true, "ol": true, "li": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"table": true, "header": true, "footer": true, "aside": true, "nav": true, "hr": true,
+}
+
+// Simplify the structure below root without altering the rendered text: remove
+// elements left empty, unwrap attribute-less wrappers with a single child and merge
+// adjacent text nodes.
+func Simplify(root *html.Node, opts SimplifyOptions) SimplifyCounts {
+	var counts SimplifyCounts
+	ignored := map[string]bool{}
+	for _, a := range opts.IgnoredAttributes {
+		ignored[a] = true
+	}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			if c.Type == html.ElementNode {
+				walk(c)
+				switch {
+				case isRemovableEmpty(c, ignored):
+					n.RemoveChild(c)
+					counts.Removed++
+				case unwrap(c, ignored):
+					counts.Unwrapped++
+				}
+			}
+			c = next
+		}
+		counts.Merged += mergeTextNodes(n)
+	}
+	walk(root)
+	return counts
+}
+
+// Reports whether the element has no attributes besides the ignored ones.
+func hasOnlyIgnoredAttributes(n *html.Node, ignored map[string]bool) bool {
+	for _, a := range n.Attr {
+		if !ignored[a.Key] {
+			return false
+		}
+	}
+	return true
+}
+
+// Reports whether the element is empty and can go without changing the rendered text.
+// Inline elements must be completely empty, block elements may contain whitespace.
+func isRemovableEmpty(n *html.Node, ignored map[string]bool) bool {
+	if keepWhenEmpty[n.Data] || !hasOnlyIgnoredAttributes(n, ignored) {
+		return false
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode || !blockElements[n.Data] {
+			return false
+		}
+		if c.Type == html.TextNode && strings.TrimSpace(c.Data) != "" {
+			return false
+		}
+	}
+	return n.Data != "pre"
+}
+
+// Replace a wrapper by its only child. A <div> or <section> is only replaced by a block
+// element, so that the layout of the text stays the same; whitespace around that child
+// is dropped. A <span> is only replaced if the child is its sole node.
+// Reports whether the wrapper was replaced.
+func unwrap(n *html.Node, ignored map[string]bool) bool {
+	if n.Parent == nil || !hasOnlyIgnoredAttributes(n, ignored) {
+		return false
+	}
+	var child *html.Node
+	nodes := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes++
+		switch {
+		case c.Type == html.ElementNode && child == nil:
+			child = c
+		case c.Type == html.TextNode && strings.TrimSpace(c.Data) == "":
+		default:
+			return false
+		}
+	}
+	if child == nil {
+		return false
+	}
+	switch n.Data {
+	case "div", "section":
+		if !blockElements[child.Data] {
+			return false
+		}
+	case "span":
+		if nodes != 1 {
+			return false
+		}
+	default:
+		return false
+	}
+	n.RemoveChild(child)
+	n.Parent.InsertBefore(child, n)
+	n.Parent.RemoveChild(n)
+	return true
+}
+
+// Merge adjacent text nodes among the children of n. Returns the number of merges.
+func mergeTextNodes(n *html.Node) int {
+	merged := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		for c.Type == html.TextNode && c.NextSibling != nil && c.NextSibling.Type == html.TextNode {
+			next := c.NextSibling
+			c.Data += next.Data
+			n.RemoveChild(next)
+			merged++
+		}
+	}
+	return merged
+}
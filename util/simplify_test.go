@@ -0,0 +1,111 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		opts   SimplifyOptions
+		want   string
+		counts SimplifyCounts
+	}{
+		{"nested wrappers",
+			`<div><div> <p>Text</p> </div></div>`,
+			SimplifyOptions{},
+			`<p>Text</p>`,
+			SimplifyCounts{Unwrapped: 2}},
+		{"inline child kept in div",
+			`<div><span>Text</span></div>`,
+			SimplifyOptions{},
+			`<div><span>Text</span></div>`,
+			SimplifyCounts{}},
+		{"span unwrapped",
+			`<p>A <span><a href="b">link</a></span>.</p>`,
+			SimplifyOptions{},
+			`<p>A <a href="b">link</a>.</p>`,
+			SimplifyCounts{Unwrapped: 1}},
+		{"attributes prevent unwrapping",
+			`<div class="l"><p id="x">Text</p></div>`,
+			SimplifyOptions{},
+			`<div class="l"><p id="x">Text</p></div>`,
+			SimplifyCounts{}},
+		{"ignored attributes",
+			`<div class="l"><div style="margin: 0"><p>Text</p></div></div>`,
+			SimplifyOptions{IgnoredAttributes: []string{"class", "style"}},
+			`<p>Text</p>`,
+			SimplifyCounts{Unwrapped: 2}},
+		{"hidden wrapper kept",
+			`<div class="l"><div style="display:none"><p>Hidden tracking note.</p></div></div>`,
+			SimplifyOptions{IgnoredAttributes: []string{"class"}},
+			`<div style="display:none"><p>Hidden tracking note.</p></div>`,
+			SimplifyCounts{Unwrapped: 1}},
+		{"empty list item kept",
+			`<ol><li>One</li><li></li><li>Three</li></ol>`,
+			SimplifyOptions{},
+			`<ol><li>One</li><li></li><li>Three</li></ol>`,
+			SimplifyCounts{}},
+		{"empty elements",
+			`<p>One</p> <div> <div></div> </div> <p>Two<br/><img src="a.png"/></p>`,
+			SimplifyOptions{},
+			`<p>One</p>  <p>Two<br/><img src="a.png"/></p>`,
+			SimplifyCounts{Removed: 2, Merged: 2}},
+		{"whitespace in inline element kept",
+			`<p>One<span> </span>two</p>`,
+			SimplifyOptions{},
+			`<p>One<span> </span>two</p>`,
+			SimplifyCounts{}},
+		{"text merged",
+			`<p>One<em></em> two</p>`,
+			SimplifyOptions{},
+			`<p>One two</p>`,
+			SimplifyCounts{Removed: 1, Merged: 1}},
+		{"anchor target kept",
+			`<h2><a id="intro"></a>Intro</h2>`,
+			SimplifyOptions{},
+			`<h2><a id="intro"></a>Intro</h2>`,
+			SimplifyCounts{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := mustParse(t, "<body>"+tt.doc+"</body>")
+			body := FindElement(doc, "body")
+			// Whitespace around blocks is not part of the rendered text.
+			text := strings.Join(strings.Fields(TextContent(body)), " ")
+			counts := Simplify(body, tt.opts)
+			if got, want := renderNode(t, body), "<body>"+tt.want+"</body>"; got != want {
+				t.Errorf("Simplify() =\n%s\nwant\n%s", got, want)
+			}
+			if counts != tt.counts {
+				t.Errorf("Simplify() counts = %+v, want %+v", counts, tt.counts)
+			}
+			if got := strings.Join(strings.Fields(TextContent(body)), " "); got != text {
+				t.Errorf("text changed from %q to %q", text, got)
+			}
+		})
+	}
+}
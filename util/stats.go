@@ -24,15 +24,87 @@ package util
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
+// Outcome of processing a single file.
+type FileStatus string
+
+const (
+	StatusSuccess FileStatus = "success"
+	StatusFailed  FileStatus = "failed"
+	StatusSkipped FileStatus = "skipped"
+)
+
+// Record describing the processing of a single file.
+type FileRecord struct {
+	Name     string        `json:"name"`
+	Status   FileStatus    `json:"status"`
+	ISize    int64         `json:"originalSize"`
+	OSize    int64         `json:"shrinkedSize"`
+	Duration time.Duration `json:"durationNs"`
+	Reason   string        `json:"reason,omitempty"`
+	Warnings []string      `json:"warnings,omitempty"`
+}
+
+// Ratio of shrinked size to original size; 0 if the original size is unknown.
+func (r FileRecord) CompressionRatio() float64 {
+	if r.ISize <= 0 {
+		return 0
+	}
+	return float64(r.OSize) / float64(r.ISize)
+}
+
+// Processed input bytes per second; 0 if no duration was measured.
+func (r FileRecord) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.ISize) / r.Duration.Seconds()
+}
+
+// Stats collects the results of a run. It is safe for concurrent use.
 type Stats struct {
-	count int
-	iSize int64
-	oSize int64
-	start time.Time
-	stop  time.Time
+	mu      sync.Mutex
+	count   int
+	iSize   int64
+	oSize   int64
+	start   time.Time
+	stop    time.Time
+	records []FileRecord
+	// Named counters contributed by the cleanup passes, e.g. unwrapped links.
+	counters map[string]int64
+	// Words and reading time of the shrinked articles, articles per language.
+	words          int64
+	readingMinutes int64
+	languages      map[string]int
+}
+
+// Point-in-time copy of the statistics, suitable for reports.
+type StatsSnapshot struct {
+	Processed        int              `json:"processed"`
+	Failed           int              `json:"failed"`
+	Skipped          int              `json:"skipped"`
+	Suspicious       int              `json:"suspicious"`
+	OriginalSize     int64            `json:"originalSize"`
+	ShrinkedSize     int64            `json:"shrinkedSize"`
+	SizeReducedBy    int64            `json:"sizeReducedBy"`
+	ElapsedMs        int64            `json:"elapsedMs"`
+	BestRatio        float64          `json:"bestRatio"`
+	WorstRatio       float64          `json:"worstRatio"`
+	MedianRatio      float64          `json:"medianRatio"`
+	BestThroughput   float64          `json:"bestThroughput"`
+	WorstThroughput  float64          `json:"worstThroughput"`
+	MedianThroughput float64          `json:"medianThroughput"`
+	Files            []FileRecord     `json:"files"`
+	Counters         map[string]int64 `json:"counters,omitempty"`
+	Words            int64            `json:"words"`
+	ReadingMinutes   int64            `json:"readingMinutes"`
+	// Number of articles per language, "unknown" if it could not be determined.
+	Languages map[string]int `json:"languages,omitempty"`
 }
 
 func NewStats() *Stats {
@@ -43,48 +115,191 @@ func NewStats() *Stats {
 	}
 }
 
-func (s *Stats) AddSizes(isize, osize int64) {
+// Record a successfully shrinked file together with any validation warnings.
+func (s *Stats) AddSuccess(name string, isize, osize int64, duration time.Duration, warnings ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.iSize += isize
 	s.oSize += osize
 	s.count++
+	s.records = append(s.records, FileRecord{Name: name, Status: StatusSuccess, ISize: isize, OSize: osize, Duration: duration, Warnings: warnings})
+}
+
+// Replace the output size recorded for the successfully shrinked file, e.g. after
+// the output was changed once more.
+func (s *Stats) UpdateOutputSize(name string, osize int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if r := &s.records[i]; r.Name == name && r.Status == StatusSuccess {
+			s.oSize += osize - r.OSize
+			r.OSize = osize
+			return
+		}
+	}
+}
+
+// Record a file which could not be processed.
+func (s *Stats) AddFailure(name string, err error, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, FileRecord{Name: name, Status: StatusFailed, Duration: duration, Reason: err.Error()})
+}
+
+// Record a file which was skipped deliberately.
+func (s *Stats) AddSkip(name, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, FileRecord{Name: name, Status: StatusSkipped, Reason: reason})
+}
+
+// Add n to the named counter.
+func (s *Stats) AddCounter(name string, n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counters == nil {
+		s.counters = map[string]int64{}
+	}
+	s.counters[name] += n
+}
+
+// Add the reading information of a shrinked article to the totals.
+func (s *Stats) AddReading(r ReadingInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.words += int64(r.Words)
+	s.readingMinutes += int64(r.ReadingMinutes)
+	if s.languages == nil {
+		s.languages = map[string]int{}
+	}
+	lang := r.Language
+	if lang == "" {
+		lang = "unknown"
+	}
+	s.languages[lang]++
 }
 
 // Calculates the saved space.
 func (s *Stats) SizeReducedBy() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.iSize - s.oSize
 }
 
 // Returns the number of processed files.
 func (s *Stats) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.count
 }
 
 // Returns the cumulated sizes of original files.
 func (s *Stats) CumulatedSizesOfOriginalFiles() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.iSize
 }
 
 // Returns the cumulated sizes of the shrinked files.
 func (s *Stats) CumulatedSizesOfShrinkedFiles() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.oSize
 }
 
 // Start time measurement.
 func (s *Stats) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.start = time.Now()
 }
 
 // Stop time measurement
 func (s *Stats) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.stop = time.Now()
 }
 
 // Retrieve the elapsed time.
 func (s *Stats) ElapsedTime() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.elapsedTime()
+}
+
+func (s *Stats) elapsedTime() int64 {
 	dur := s.stop.Sub(s.start)
 	return dur.Milliseconds()
 }
 
+// Take a consistent copy of the collected statistics.
+// Ratios and throughputs are calculated from successfully processed files only.
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := StatsSnapshot{
+		Processed:      s.count,
+		OriginalSize:   s.iSize,
+		ShrinkedSize:   s.oSize,
+		SizeReducedBy:  s.iSize - s.oSize,
+		ElapsedMs:      s.elapsedTime(),
+		Files:          append([]FileRecord(nil), s.records...),
+		Words:          s.words,
+		ReadingMinutes: s.readingMinutes,
+	}
+	if len(s.languages) > 0 {
+		snap.Languages = map[string]int{}
+		for lang, n := range s.languages {
+			snap.Languages[lang] = n
+		}
+	}
+	if len(s.counters) > 0 {
+		snap.Counters = map[string]int64{}
+		for name, n := range s.counters {
+			snap.Counters[name] = n
+		}
+	}
+	var ratios, throughputs []float64
+	for _, r := range s.records {
+		switch r.Status {
+		case StatusFailed:
+			snap.Failed++
+		case StatusSkipped:
+			snap.Skipped++
+		case StatusSuccess:
+			if len(r.Warnings) > 0 {
+				snap.Suspicious++
+			}
+			if r.ISize > 0 {
+				ratios = append(ratios, r.CompressionRatio())
+			}
+			if r.Duration > 0 {
+				throughputs = append(throughputs, r.Throughput())
+			}
+		}
+	}
+	// A low ratio means strong compression, so the best ratio is the smallest one.
+	snap.BestRatio, snap.MedianRatio, snap.WorstRatio = minMedianMax(ratios)
+	snap.WorstThroughput, snap.MedianThroughput, snap.BestThroughput = minMedianMax(throughputs)
+	return snap
+}
+
+// Determine minimum, median and maximum of the given values.
+func minMedianMax(values []float64) (float64, float64, float64) {
+	if len(values) == 0 {
+		return 0, 0, 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	median := sorted[n/2]
+	if n%2 == 0 {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return sorted[0], median, sorted[n-1]
+}
+
 func GetFileSize(name string) int64 {
 	fstat, err := os.Stat(name)
 	if err != nil {
@@ -93,14 +308,58 @@ func GetFileSize(name string) int64 {
 	return fstat.Size()
 }
 
+// Unit system used to format sizes.
+type SizeUnits int
+
+const (
+	// Binary units based on 1024: KiB, MiB, GiB, TiB.
+	UnitsIEC SizeUnits = iota
+	// Decimal units based on 1000: kB, MB, GB, TB.
+	UnitsSI
+)
+
+var unitLabels = map[SizeUnits][]string{
+	UnitsIEC: {"B", "KiB", "MiB", "GiB", "TiB"},
+	UnitsSI:  {"B", "kB", "MB", "GB", "TB"},
+}
+
+// Parse the name of a unit system as given on the command line.
+func ParseSizeUnits(name string) (SizeUnits, error) {
+	switch strings.ToLower(name) {
+	case "iec", "binary":
+		return UnitsIEC, nil
+	case "si", "decimal":
+		return UnitsSI, nil
+	}
+	return UnitsIEC, fmt.Errorf("unknown size units %q, expected iec or si", name)
+}
+
+// Format the given size in IEC units.
 func FormatFileSize(size int64) string {
-	if size > 1024 {
-		sz := float64(size) / 1024
-		if sz > 1024 {
-			sz = sz / 1024
-			return fmt.Sprintf("%.2f MB", sz)
-		}
-		return fmt.Sprintf("%.2f KB", sz)
+	return FormatSize(size, UnitsIEC)
+}
+
+// Format the given size using the given unit system.
+// Negative sizes are formatted with a leading minus, e.g. if shrinking made a file grow.
+func FormatSize(size int64, units SizeUnits) string {
+	base := 1024.0
+	if units == UnitsSI {
+		base = 1000.0
+	}
+	labels := unitLabels[units]
+	sign := ""
+	abs := float64(size)
+	if size < 0 {
+		sign = "-"
+		abs = -abs
+	}
+	if abs < base {
+		return fmt.Sprintf("%s%d %s", sign, int64(abs), labels[0])
+	}
+	idx := 0
+	for abs >= base && idx < len(labels)-1 {
+		abs /= base
+		idx++
 	}
-	return fmt.Sprintf("%d B", size)
+	return fmt.Sprintf("%s%.2f %s", sign, abs, labels[idx])
 }
@@ -22,8 +22,12 @@ THE SOFTWARE.
 package util
 
 import (
+	"errors"
+	"math"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewStats(t *testing.T) {
@@ -42,7 +46,7 @@ func TestNewStats(t *testing.T) {
 	}
 }
 
-func TestStats_AddSizes(t *testing.T) {
+func TestStats_AddSuccess(t *testing.T) {
 	type fields struct {
 		count int
 		iSize int64
@@ -68,9 +72,9 @@ func TestStats_AddSizes(t *testing.T) {
 				iSize: tt.fields.iSize,
 				oSize: tt.fields.oSize,
 			}
-			s.AddSizes(tt.args.isize, tt.args.osize)
-			if !reflect.DeepEqual(s, tt.want) {
-				t.Errorf("NewStats() = %v, want %v", s, tt.want)
+			s.AddSuccess("f.html", tt.args.isize, tt.args.osize, time.Second)
+			if s.count != tt.want.count || s.iSize != tt.want.iSize || s.oSize != tt.want.oSize || len(s.records) != 1 {
+				t.Errorf("AddSuccess() = %v, want %v", s, tt.want)
 			}
 		})
 	}
@@ -114,9 +118,14 @@ func Test_formatFileSize(t *testing.T) {
 		want string
 	}{
 		{"Bytes", args{size: 750}, "750 B"},
-		{"KBytes", args{size: 5000}, "4.88 KB"},
-		{"MBytes", args{size: 5000000}, "4.77 MB"},
-		{"GBytes", args{size: 5000000000}, "4768.37 MB"},
+		{"Below KiB", args{size: 1023}, "1023 B"},
+		{"One KiB", args{size: 1024}, "1.00 KiB"},
+		{"One MiB", args{size: 1 << 20}, "1.00 MiB"},
+		{"KBytes", args{size: 5000}, "4.88 KiB"},
+		{"MBytes", args{size: 5000000}, "4.77 MiB"},
+		{"GBytes", args{size: 5000000000}, "4.66 GiB"},
+		{"TBytes", args{size: 5000000000000}, "4.55 TiB"},
+		{"Negative", args{size: -5000}, "-4.88 KiB"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -126,3 +135,151 @@ func Test_formatFileSize(t *testing.T) {
 		})
 	}
 }
+
+func TestStats_Snapshot(t *testing.T) {
+	s := NewStats()
+	s.AddSuccess("a.html", 1000, 200, 100*time.Millisecond)
+	s.AddSuccess("b.html", 1000, 500, 200*time.Millisecond)
+	s.AddSuccess("c.html", 2000, 1600, 400*time.Millisecond)
+	s.AddFailure("d.html", errors.New("no <article> element"), time.Millisecond)
+	s.AddSkip("e.html", "already shrunk")
+
+	snap := s.Snapshot()
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"processed", float64(snap.Processed), 3},
+		{"failed", float64(snap.Failed), 1},
+		{"skipped", float64(snap.Skipped), 1},
+		{"size reduced", float64(snap.SizeReducedBy), 1700},
+		{"best ratio", snap.BestRatio, 0.2},
+		{"median ratio", snap.MedianRatio, 0.5},
+		{"worst ratio", snap.WorstRatio, 0.8},
+		{"best throughput", snap.BestThroughput, 10000},
+		{"median throughput", snap.MedianThroughput, 5000},
+		{"worst throughput", snap.WorstThroughput, 5000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if math.Abs(tt.got-tt.want) > 1e-9 {
+				t.Errorf("Snapshot() %s = %v, want %v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+	if len(snap.Files) != 5 {
+		t.Errorf("Snapshot() recorded %d files, want 5", len(snap.Files))
+	}
+}
+
+func TestStats_ConcurrentAdd(t *testing.T) {
+	s := NewStats()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.AddSuccess("f.html", 100, 10, time.Millisecond)
+		}()
+	}
+	wg.Wait()
+	if got := s.Count(); got != 50 {
+		t.Errorf("Count() = %v, want 50", got)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	type args struct {
+		size  int64
+		units SizeUnits
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{"SI bytes", args{size: 750, units: UnitsSI}, "750 B"},
+		{"SI below kilobyte", args{size: 999, units: UnitsSI}, "999 B"},
+		{"SI one kilobyte", args{size: 1000, units: UnitsSI}, "1.00 kB"},
+		{"SI one megabyte", args{size: 1000000, units: UnitsSI}, "1.00 MB"},
+		{"SI kilobytes", args{size: 5000, units: UnitsSI}, "5.00 kB"},
+		{"SI megabytes", args{size: 5000000, units: UnitsSI}, "5.00 MB"},
+		{"SI gigabytes", args{size: 5000000000, units: UnitsSI}, "5.00 GB"},
+		{"SI terabytes", args{size: 5000000000000, units: UnitsSI}, "5.00 TB"},
+		{"SI negative", args{size: -2500, units: UnitsSI}, "-2.50 kB"},
+		{"IEC negative bytes", args{size: -12, units: UnitsIEC}, "-12 B"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatSize(tt.args.size, tt.args.units); got != tt.want {
+				t.Errorf("FormatSize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSizeUnits(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		want    SizeUnits
+		wantErr bool
+	}{
+		{"iec", "iec", UnitsIEC, false},
+		{"SI upper case", "SI", UnitsSI, false},
+		{"unknown", "metric", UnitsIEC, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSizeUnits(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSizeUnits() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseSizeUnits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStats_AddCounter(t *testing.T) {
+	s := NewStats()
+	if snap := s.Snapshot(); snap.Counters != nil {
+		t.Errorf("Snapshot() counters = %v, want nil", snap.Counters)
+	}
+	s.AddCounter("links unwrapped", 2)
+	s.AddCounter("links unwrapped", 3)
+	s.AddCounter("links normalized", 1)
+	want := map[string]int64{"links unwrapped": 5, "links normalized": 1}
+	if got := s.Snapshot().Counters; !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot() counters = %v, want %v", got, want)
+	}
+}
+
+func TestStats_UpdateOutputSize(t *testing.T) {
+	s := NewStats()
+	s.AddSuccess("a.html", 1000, 400, time.Second)
+	s.AddSuccess("b.html", 500, 200, time.Second)
+	s.UpdateOutputSize("a.html", 450)
+	s.UpdateOutputSize("unknown.html", 1)
+	snap := s.Snapshot()
+	if snap.ShrinkedSize != 650 || snap.Files[0].OSize != 450 || snap.Files[1].OSize != 200 {
+		t.Errorf("Snapshot() shrinked size = %d, files = %+v", snap.ShrinkedSize, snap.Files)
+	}
+}
+
+func TestStats_AddReading(t *testing.T) {
+	s := NewStats()
+	s.AddReading(ReadingInfo{Words: 1000, ReadingMinutes: 5, Language: "en"})
+	s.AddReading(ReadingInfo{Words: 460, ReadingMinutes: 2, Language: "en"})
+	s.AddReading(ReadingInfo{Words: 20, ReadingMinutes: 1})
+	snap := s.Snapshot()
+	if snap.Words != 1480 || snap.ReadingMinutes != 8 {
+		t.Errorf("Snapshot() words = %d, minutes = %d", snap.Words, snap.ReadingMinutes)
+	}
+	if snap.Languages["en"] != 2 || snap.Languages["unknown"] != 1 {
+		t.Errorf("Snapshot() languages = %v", snap.Languages)
+	}
+}
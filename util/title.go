@@ -0,0 +1,212 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Origin of a resolved title.
+type TitleSource string
+
+const (
+	TitleFromTitleTag  TitleSource = "title"
+	TitleFromOpenGraph TitleSource = "og:title"
+	TitleFromTwitter   TitleSource = "twitter:title"
+	TitleFromJSONLD    TitleSource = "json-ld"
+	TitleFromHeading   TitleSource = "h1"
+)
+
+// A title together with its origin.
+type ResolvedTitle struct {
+	Title  string
+	Source TitleSource
+}
+
+// Patterns matching the suffix a site appends to its titles, by site.
+// The patterns listed for the empty site name are applied to all documents.
+var titleSuffixPatterns = map[string][]*regexp.Regexp{
+	"": {
+		regexp.MustCompile(`\s+\|\s+by\s+[^|]+(\|[^|]+)*$`),
+	},
+	"medium": {
+		regexp.MustCompile(`\s+\|\s+by\s+.*$`),
+		regexp.MustCompile(`\s+\|\s+in\s+[^|]+(\|[^|]+)*$`),
+		regexp.MustCompile(`\s+\|\s+Medium$`),
+	},
+	"dev.to": {
+		regexp.MustCompile(`\s+-\s+DEV Community.*$`),
+	},
+}
+
+// Add a pattern matching the title suffix of the given site.
+func AddTitleSuffixPattern(site, expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	titleSuffixPatterns[site] = append(titleSuffixPatterns[site], re)
+	return nil
+}
+
+// Determine the site a document was clipped from, e.g. "medium".
+// Returns an empty string if the site is not known.
+func DetectSite(doc *html.Node) string {
+	for _, key := range []string{"al:android:app_name", "twitter:app:name:iphone", "og:site_name"} {
+		if strings.EqualFold(MetaContent(doc, key), "Medium") {
+			return "medium"
+		}
+	}
+	for _, generator := range MetaContents(doc, "generator") {
+		generator = strings.ToLower(generator)
+		for _, site := range []string{"substack", "ghost", "wordpress"} {
+			if strings.HasPrefix(generator, site) {
+				return site
+			}
+		}
+	}
+	if FirstElement(doc, AttributeContains("href", "substackcdn.com")) != nil ||
+		FirstElement(doc, AttributeContains("src", "substackcdn.com")) != nil {
+		return "substack"
+	}
+	if u, err := url.Parse(LinkHref(doc, "canonical")); err == nil {
+		host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+		switch {
+		case host == "medium.com", strings.HasSuffix(host, ".medium.com"):
+			return "medium"
+		case strings.HasSuffix(host, ".substack.com"):
+			return "substack"
+		case host == "dev.to":
+			return "dev.to"
+		}
+	}
+	return ""
+}
+
+// Remove the site specific suffix and the site names from the given title.
+func CleanTitle(title, site string, siteNames ...string) string {
+	cleaned := strings.Join(strings.Fields(title), " ")
+	patterns := titleSuffixPatterns[""]
+	if site != "" {
+		patterns = append(patterns[:len(patterns):len(patterns)], titleSuffixPatterns[site]...)
+	}
+	for _, re := range patterns {
+		if stripped := strings.TrimSpace(re.ReplaceAllString(cleaned, "")); stripped != "" {
+			cleaned = stripped
+		}
+	}
+	for _, siteName := range siteNames {
+		if siteName == "" {
+			continue
+		}
+		for _, sep := range []string{" | ", " - ", " – ", " — ", " · "} {
+			if stripped, ok := strings.CutSuffix(cleaned, sep+siteName); ok && stripped != "" {
+				cleaned = stripped
+			}
+		}
+	}
+	return cleaned
+}
+
+// The names the document gives its site: og:site_name, application-name and the
+// text of the element the profile of the site names it by.
+func SiteNames(doc *html.Node) []string {
+	var names []string
+	for _, key := range []string{"og:site_name", "application-name"} {
+		if name := strings.Join(strings.Fields(MetaContent(doc, key)), " "); name != "" {
+			names = append(names, name)
+		}
+	}
+	if p, ok := DetectProfile(doc); ok && p.SiteName != nil {
+		if n := FirstElement(doc, p.SiteName); n != nil {
+			if name := strings.Join(strings.Fields(TextContent(n)), " "); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// Determine the title of the document. Tries <title>, og:title, twitter:title,
+// the JSON-LD headline and the first <h1> of the article in that order.
+// If a later source is a leading part of the chosen title, e.g. the title without
+// a subtitle, that one is taken instead.
+// The boolean result is false if no title could be found at all.
+func ResolveTitle(doc *html.Node) (ResolvedTitle, bool) {
+	site := DetectSite(doc)
+	siteNames := SiteNames(doc)
+	var candidates []ResolvedTitle
+	add := func(title string, source TitleSource) {
+		if cleaned := CleanTitle(title, site, siteNames...); cleaned != "" {
+			candidates = append(candidates, ResolvedTitle{Title: cleaned, Source: source})
+		}
+	}
+	if head := FindElement(doc, "head"); head != nil {
+		if t := FindElement(head, "title"); t != nil {
+			add(TextContent(t), TitleFromTitleTag)
+		}
+	}
+	add(MetaContent(doc, "og:title"), TitleFromOpenGraph)
+	add(MetaContent(doc, "twitter:title"), TitleFromTwitter)
+	for _, obj := range JSONLDObjects(doc) {
+		if headline := JSONLDStrings(obj["headline"]); len(headline) > 0 {
+			add(headline[0], TitleFromJSONLD)
+			break
+		}
+	}
+	root := FindElement(doc, "article")
+	if root == nil {
+		root = doc
+	}
+	if h1 := FindElement(root, "h1"); h1 != nil {
+		add(TextContent(h1), TitleFromHeading)
+	}
+	if len(candidates) == 0 {
+		return ResolvedTitle{}, false
+	}
+	chosen := candidates[0]
+	for _, c := range candidates[1:] {
+		if isLeadingPart(c.Title, chosen.Title) {
+			chosen = c
+		}
+	}
+	return chosen, true
+}
+
+// Reports whether part is a proper leading part of title, followed by a sentence
+// or clause separator.
+func isLeadingPart(part, title string) bool {
+	rest, ok := strings.CutPrefix(title, part)
+	if !ok || rest == "" {
+		return false
+	}
+	for _, sep := range []string{". ", ": ", " - ", " – ", " — ", "? ", "! "} {
+		if strings.HasPrefix(rest, sep) {
+			return true
+		}
+	}
+	return false
+}
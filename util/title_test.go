@@ -0,0 +1,96 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"testing"
+)
+
+func TestResolveTitle(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        string
+		want       string
+		wantSource TitleSource
+		wantFound  bool
+	}{
+		{"title tag", `<html><head><title>Plain title</title></head><body></body></html>`,
+			"Plain title", TitleFromTitleTag, true},
+		{"medium suffix", `<html><head><title>Go 1.22. What's new | by Jane Doe | Medium</title></head><body></body></html>`,
+			"Go 1.22. What's new", TitleFromTitleTag, true},
+		{"medium publication", `<html><head><meta property="al:android:app_name" content="Medium"><title>Title | in Better Programming | Medium</title></head><body></body></html>`,
+			"Title", TitleFromTitleTag, true},
+		{"site name", `<html><head><meta property="og:site_name" content="The Blog"><title>Post – The Blog</title></head><body></body></html>`,
+			"Post", TitleFromTitleTag, true},
+		{"dash in title kept", `<html><head><meta property="og:site_name" content="Crumb"><title>Go – the good parts</title></head><body></body></html>`,
+			"Go – the good parts", TitleFromTitleTag, true},
+		{"only site name stripped", `<html><head><meta property="og:site_name" content="Crumb"><title>Part 1 – Getting started – Crumb</title>` +
+			`<meta property="og:title" content="Part 1 – Getting started"></head><body></body></html>`,
+			"Part 1 – Getting started", TitleFromTitleTag, true},
+		{"site name of the profile", `<html><head><meta name="generator" content="WordPress 6.4"><title>Go – the good parts – Crumb</title></head>` +
+			`<body><p class="site-title"><a href="/">Crumb</a></p></body></html>`,
+			"Go – the good parts", TitleFromTitleTag, true},
+		{"subtitle dropped by heading", `<html><head><title>Main title. A subtitle here | by X | Medium</title></head><body><article><h1>Main title</h1></article></body></html>`,
+			"Main title", TitleFromHeading, true},
+		{"og fallback", `<html><head><title> </title><meta property="og:title" content="From OpenGraph"></head><body></body></html>`,
+			"From OpenGraph", TitleFromOpenGraph, true},
+		{"twitter fallback", `<html><head><meta name="twitter:title" content="From Twitter"></head><body></body></html>`,
+			"From Twitter", TitleFromTwitter, true},
+		{"json-ld fallback", `<html><head><script type="application/ld+json">{"@graph":[{"@type":"Article","headline":"From JSON-LD"}]}</script></head><body></body></html>`,
+			"From JSON-LD", TitleFromJSONLD, true},
+		{"heading fallback", `<html><head></head><body><article><h1>From <em>heading</em></h1></article></body></html>`,
+			"From heading", TitleFromHeading, true},
+		{"no title", `<html><head></head><body><p>Nothing</p></body></html>`,
+			"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := ResolveTitle(mustParse(t, tt.doc))
+			if found != tt.wantFound {
+				t.Fatalf("ResolveTitle() found = %v, want %v", found, tt.wantFound)
+			}
+			if got.Title != tt.want || got.Source != tt.wantSource {
+				t.Errorf("ResolveTitle() = %q from %s, want %q from %s", got.Title, got.Source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestDetectSite(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"medium app name", `<html><head><meta property="al:android:app_name" content="Medium"></head></html>`, "medium"},
+		{"medium canonical", `<html><head><link rel="canonical" href="https://blog.medium.com/x-123"></head></html>`, "medium"},
+		{"substack", `<html><head><link rel="canonical" href="https://someone.substack.com/p/post"></head></html>`, "substack"},
+		{"unknown", `<html><head></head></html>`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectSite(mustParse(t, tt.doc)); got != tt.want {
+				t.Errorf("DetectSite() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
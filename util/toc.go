@@ -0,0 +1,188 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// A heading listed in the table of contents.
+type Heading struct {
+	Level int
+	Text  string
+	ID    string
+}
+
+// Levels of the headings listed in the table of contents.
+var tocHeadings = map[string]int{"h1": 1, "h2": 2, "h3": 3, "h4": 4}
+
+// Derive an ID from the text of a heading: lower case letters and digits with words
+// separated by hyphens. Apostrophes are dropped, other characters separate words.
+func Slugify(text string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		case r == '\'' || r == '’':
+		default:
+			hyphen = true
+		}
+	}
+	if b.Len() == 0 {
+		return "section"
+	}
+	return b.String()
+}
+
+// Collect the h1 to h4 headings below root in document order and give those without
+// an ID one derived from their text. Existing IDs are kept; a number is appended to
+// derived IDs taken already in the document.
+func AssignHeadingIDs(root *html.Node) []Heading {
+	used := map[string]bool{}
+	var elements []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if id := GetAttribute(n, "id"); id != "" {
+				used[id] = true
+			}
+			if tocHeadings[n.Data] > 0 {
+				elements = append(elements, n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	var headings []Heading
+	for _, n := range elements {
+		text := strings.Join(strings.Fields(TextContent(n)), " ")
+		if text == "" {
+			continue
+		}
+		id := GetAttribute(n, "id")
+		if id == "" {
+			slug := Slugify(text)
+			id = slug
+			for i := 2; used[id]; i++ {
+				id = fmt.Sprintf("%s-%d", slug, i)
+			}
+			used[id] = true
+			setAttribute(n, "id", id)
+		}
+		headings = append(headings, Heading{Level: tocHeadings[n.Data], Text: text, ID: id})
+	}
+	return headings
+}
+
+// An entry of the table of contents and the entries nested below it.
+type tocEntry struct {
+	heading  Heading
+	children []*tocEntry
+}
+
+// Build a table of contents as nested lists of links to the headings.
+// Returns nil if there are no headings.
+func BuildTOC(headings []Heading) *html.Node {
+	if len(headings) == 0 {
+		return nil
+	}
+	nav := newElement("nav", "class", "shrinkr-toc")
+	nav.AppendChild(tocList(tocTree(headings)))
+	return nav
+}
+
+// Build a table of contents as nested Markdown lists of links to the headings, for
+// Markdown renderings of the output. Returns an empty string if there are no headings.
+func MarkdownTOC(headings []Heading) string {
+	var b strings.Builder
+	var write func(entries []*tocEntry, indent string)
+	write = func(entries []*tocEntry, indent string) {
+		for _, e := range entries {
+			fmt.Fprintf(&b, "%s1. [%s](#%s)\n", indent, markdownEscaper.Replace(e.heading.Text), e.heading.ID)
+			write(e.children, indent+"   ")
+		}
+	}
+	write(tocTree(headings), "")
+	return b.String()
+}
+
+// Escapes the characters ending the text of a Markdown link.
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`)
+
+// Nest the headings by their levels.
+func tocTree(headings []Heading) []*tocEntry {
+	type level struct {
+		level   int
+		entries *[]*tocEntry
+	}
+	var top []*tocEntry
+	stack := []level{{0, &top}}
+	for _, h := range headings {
+		for stack[len(stack)-1].level >= h.Level {
+			stack = stack[:len(stack)-1]
+		}
+		e := &tocEntry{heading: h}
+		parent := stack[len(stack)-1].entries
+		*parent = append(*parent, e)
+		stack = append(stack, level{h.Level, &e.children})
+	}
+	return top
+}
+
+func tocList(entries []*tocEntry) *html.Node {
+	ol := newElement("ol")
+	for _, e := range entries {
+		li := newElement("li")
+		li.AppendChild(withText(newElement("a", "href", "#"+e.heading.ID), e.heading.Text))
+		if len(e.children) > 0 {
+			li.AppendChild(tocList(e.children))
+		}
+		ol.AppendChild(li)
+	}
+	return ol
+}
+
+// Insert a table of contents of the headings below root at the top of the <body>.
+// Returns the number of headings listed.
+func InsertTOC(doc, root *html.Node) int {
+	body := FindElement(doc, "body")
+	headings := AssignHeadingIDs(root)
+	toc := BuildTOC(headings)
+	if body == nil || toc == nil {
+		return 0
+	}
+	body.InsertBefore(toc, body.FirstChild)
+	return len(headings)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Writing Table-Driven Tests": "writing-table-driven-tests",
+		"  What's new in Go 1.22?  ": "whats-new-in-go-1-22",
+		"Über große Dateien":         "über-große-dateien",
+		"!!!":                        "section",
+	}
+	for text, want := range tests {
+		if got := Slugify(text); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestInsertTOC(t *testing.T) {
+	doc := mustParse(t, `<body><article><h1>Title</h1><p>Intro</p><h2 id="setup">Setup</h2><h3>Details</h3>`+
+		`<h2>Usage</h2><h4>Deep</h4><h2>Usage</h2><h5>Too deep</h5><h2> </h2><p id="usage-3">Taken</p><h2>Usage</h2></article></body>`)
+	n := InsertTOC(doc, FindElement(doc, "article"))
+	if n != 7 {
+		t.Errorf("InsertTOC() listed %d headings, want 7", n)
+	}
+	want := `<body><nav class="shrinkr-toc"><ol><li><a href="#title">Title</a><ol>` +
+		`<li><a href="#setup">Setup</a><ol><li><a href="#details">Details</a></li></ol></li>` +
+		`<li><a href="#usage">Usage</a><ol><li><a href="#deep">Deep</a></li></ol></li>` +
+		`<li><a href="#usage-2">Usage</a></li>` +
+		`<li><a href="#usage-4">Usage</a></li></ol></li></ol></nav>` +
+		`<article><h1 id="title">Title</h1><p>Intro</p><h2 id="setup">Setup</h2><h3 id="details">Details</h3>` +
+		`<h2 id="usage">Usage</h2><h4 id="deep">Deep</h4><h2 id="usage-2">Usage</h2><h5>Too deep</h5><h2> </h2><p id="usage-3">Taken</p><h2 id="usage-4">Usage</h2></article></body>`
+	if got := renderNode(t, FindElement(doc, "body")); got != want {
+		t.Errorf("InsertTOC() =\n%s\nwant\n%s", got, want)
+	}
+	if doc := mustParse(t, "<p>No headings</p>"); InsertTOC(doc, doc) != 0 {
+		t.Error("InsertTOC() inserted a table of contents without headings")
+	}
+}
+
+func TestMarkdownTOC(t *testing.T) {
+	headings := []Heading{
+		{Level: 2, Text: "Setup", ID: "setup"},
+		{Level: 3, Text: "The [config] file", ID: "the-config-file"},
+		{Level: 2, Text: "Usage", ID: "usage"},
+	}
+	want := "1. [Setup](#setup)\n" +
+		"   1. [The \\[config\\] file](#the-config-file)\n" +
+		"1. [Usage](#usage)\n"
+	if got := MarkdownTOC(headings); got != want {
+		t.Errorf("MarkdownTOC() =\n%s\nwant\n%s", got, want)
+	}
+	if got := MarkdownTOC(nil); got != "" {
+		t.Errorf("MarkdownTOC(nil) = %q", got)
+	}
+}
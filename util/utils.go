@@ -25,6 +25,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"golang.org/x/net/html"
 )
@@ -91,19 +92,6 @@ func HasArticleElement(rootNode *html.Node) bool {
 	return lookupArticle(body)
 }
 
-// Looks for the <title> and returns it.
-// Panics if <title> not found.
-func LookupTitle(rootNode *html.Node) string {
-	head := LookupHead(rootNode)
-	for c := head.FirstChild; c != nil; c = c.NextSibling {
-		if c.Data == "title" {
-			title := c.FirstChild.Data
-			return title
-		}
-	}
-	panic("title tag not found")
-}
-
 // Determines the siblings of the given node.
 func ListSiblingsOfNode(n *html.Node) []*html.Node {
 	var l []*html.Node
@@ -130,3 +118,114 @@ func CreateDirIfNotExist(path string) {
 		}
 	}
 }
+
+// Search the subtree below the given node for the first element with the given tag.
+// Returns nil if there is no such element.
+func FindElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := FindElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Collects the visible text of the subtree below the given node.
+// Contents of <script>, <style> and <noscript> are ignored.
+func TextContent(n *html.Node) string {
+	var sb strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			return
+		}
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "noscript":
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return sb.String()
+}
+
+// Collects all text below the given node including scripts and styles.
+func TextContentRaw(n *html.Node) string {
+	var sb strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return sb.String()
+}
+
+// Length of the visible text below the given node with whitespace collapsed.
+func TextLength(n *html.Node) int {
+	return len(strings.Join(strings.Fields(TextContent(n)), " "))
+}
+
+// Returns the value of the given attribute or an empty string if it is not set.
+func GetAttribute(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// Returns the content of the first <meta> element whose name or property matches the given key.
+// Returns an empty string if there is no such element.
+func MetaContent(doc *html.Node, key string) string {
+	var walk func(*html.Node) string
+	walk = func(n *html.Node) string {
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			if strings.EqualFold(GetAttribute(n, "name"), key) || strings.EqualFold(GetAttribute(n, "property"), key) {
+				return strings.TrimSpace(GetAttribute(n, "content"))
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if v := walk(c); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	return walk(doc)
+}
+
+// Returns the href of the first <link> element with the given rel.
+// Returns an empty string if there is no such element.
+func LinkHref(doc *html.Node, rel string) string {
+	var walk func(*html.Node) string
+	walk = func(n *html.Node) string {
+		if n.Type == html.ElementNode && n.Data == "link" {
+			for _, r := range strings.Fields(GetAttribute(n, "rel")) {
+				if strings.EqualFold(r, rel) {
+					return strings.TrimSpace(GetAttribute(n, "href"))
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if v := walk(c); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	return walk(doc)
+}
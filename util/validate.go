@@ -0,0 +1,121 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Thresholds applied when checking the result of shrinking a document.
+type ValidationLimits struct {
+	// Minimum share of the article text which must survive shrinking.
+	MinTextRatio float64
+	// Maximum allowed ratio of output size to input size.
+	MaxSizeRatio float64
+}
+
+// Limits used if nothing else is configured.
+func DefaultValidationLimits() ValidationLimits {
+	return ValidationLimits{MinTextRatio: 0.9, MaxSizeRatio: 1.0}
+}
+
+// Measurements of a document taken before it is shrinked.
+type Baseline struct {
+	Size           int64
+	ArticleTextLen int
+	// Strategies locating the main content, by default the <article>.
+	Strategies []Strategy
+}
+
+// Measure the given document before shrinking it.
+// The main content is located with the given strategies.
+func MeasureBaseline(doc *html.Node, size int64, using []Strategy) Baseline {
+	if len(using) == 0 {
+		using = []Strategy{strategies["article"]}
+	}
+	base := Baseline{Size: size, Strategies: using}
+	if content := locateContent(doc, using); content != nil {
+		base.ArticleTextLen = TextLength(content)
+	}
+	return base
+}
+
+// Check the shrinked document against the baseline of the original. What shrinkr adds
+// itself does not count for the size, see AddedSize.
+// Returns a description of each suspicious finding; an empty result means the document looks fine.
+func ValidateShrink(base Baseline, shrunk *html.Node, shrunkSize int64, limits ValidationLimits) []string {
+	var issues []string
+	article, _, found := LocateContent(shrunk, base.Strategies)
+	if !found {
+		issues = append(issues, "no main content left in output")
+	}
+	if title := FindElement(shrunk, "title"); title == nil || strings.TrimSpace(TextContent(title)) == "" {
+		issues = append(issues, "no title in output")
+	}
+	if article != nil && base.ArticleTextLen > 0 {
+		ratio := float64(TextLength(article)) / float64(base.ArticleTextLen)
+		if ratio < limits.MinTextRatio {
+			issues = append(issues, fmt.Sprintf("only %.0f%% of the article text retained", ratio*100))
+		}
+	}
+	shrunkSize -= AddedSize(shrunk)
+	if base.Size > 0 && float64(shrunkSize) >= float64(base.Size)*limits.MaxSizeRatio {
+		issues = append(issues, fmt.Sprintf("output size %d bytes is not below %.0f%% of input size %d bytes",
+			shrunkSize, limits.MaxSizeRatio*100, base.Size))
+	}
+	return issues
+}
+
+// Determine the bytes shrinkr adds to a document itself: the charset declaration,
+// the meta tags describing provenance, reading time and flags, the table of contents
+// and the ids of the headings it links to.
+func AddedSize(doc *html.Node) int64 {
+	var size int64
+	var links []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if isProvenanceNode(n) || (n.Type == html.ElementNode && n.Data == "meta" && hasAttribute(n, "charset")) {
+			size += RenderedSize(n)
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "nav" && GetAttribute(n, "class") == "shrinkr-toc" {
+			size += RenderedSize(n)
+			for _, a := range findAll(n, "a") {
+				if id, ok := strings.CutPrefix(GetAttribute(a, "href"), "#"); ok {
+					links = append(links, id)
+				}
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	for _, id := range links {
+		size += int64(len(` id=""`) + len(html.EscapeString(id)))
+	}
+	return size
+}
@@ -0,0 +1,86 @@
+/*
+Copyright © 2024 Stefan Braun sb@action.ms
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func mustParse(t *testing.T, doc string) *html.Node {
+	t.Helper()
+	n, err := html.Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("parsing test document failed: %v", err)
+	}
+	return n
+}
+
+func TestValidateShrink(t *testing.T) {
+	original := `<html><head><title>T</title></head><body><article><p>Some article text here.</p></article><div>more</div></body></html>`
+	base := MeasureBaseline(mustParse(t, original), 1000, nil)
+	tests := []struct {
+		name       string
+		shrunk     string
+		size       int64
+		wantIssues int
+		wantText   string
+	}{
+		{"fine", `<html><head><title>T</title></head><body><article><p>Some article text here.</p></article></body></html>`, 500, 0, ""},
+		{"article lost", `<html><head><title>T</title></head><body></body></html>`, 500, 1, "no main content"},
+		{"title lost", `<html><head></head><body><article><p>Some article text here.</p></article></body></html>`, 500, 1, "no title"},
+		{"text lost", `<html><head><title>T</title></head><body><article><p>Some</p></article></body></html>`, 500, 1, "article text retained"},
+		{"grown", `<html><head><title>T</title></head><body><article><p>Some article text here.</p></article></body></html>`, 1200, 1, "not below"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := ValidateShrink(base, mustParse(t, tt.shrunk), tt.size, DefaultValidationLimits())
+			if len(issues) != tt.wantIssues {
+				t.Fatalf("ValidateShrink() = %v, want %d issues", issues, tt.wantIssues)
+			}
+			if tt.wantText != "" && !strings.Contains(issues[0], tt.wantText) {
+				t.Errorf("ValidateShrink() = %v, want issue containing %q", issues, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestValidateShrink_additionsNotCounted(t *testing.T) {
+	original := `<html><head><title>T</title><script></script></head><body><article>` +
+		`<h2>One</h2><p>First part.</p><h2>Two</h2><p>Second part.</p></article></body></html>`
+	base := MeasureBaseline(mustParse(t, original), int64(len(original)), nil)
+	doc := mustParse(t, original)
+	script := FindElement(doc, "script")
+	script.Parent.RemoveChild(script)
+	InsertTOC(doc, FindElement(doc, "article"))
+	AddProvenance(doc, Provenance{Version: "1.0.0", OriginalSize: int64(len(original)), OriginalSHA256: "abc"})
+	AddReadingInfo(doc, MeasureReading(FindElement(doc, "article")))
+	size := RenderedSize(doc)
+	if size < int64(len(original)) {
+		t.Fatalf("test document of %d bytes does not grow beyond the original", size)
+	}
+	if issues := ValidateShrink(base, doc, size, DefaultValidationLimits()); len(issues) != 0 {
+		t.Errorf("ValidateShrink() = %v, want no issues", issues)
+	}
+}